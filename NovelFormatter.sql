@@ -97,4 +97,24 @@ ALTER TABLE `chapters`
   ADD CONSTRAINT `chapters_ibfk_1`
   FOREIGN KEY (`novel_id`)
   REFERENCES `novels` (`novel_id`)
-  ON DELETE CASCADE;
\ No newline at end of file
+  ON DELETE CASCADE;
+
+-- Language of each novel (BCP 47 tag such as 'es'); NULL uses the site language
+ALTER TABLE `novels`
+  ADD COLUMN `lang` varchar(35) DEFAULT NULL AFTER `description`;
+
+-- Character glossary: highlighted names link to a page per character.
+-- aliases is a comma-separated list of other names the character goes by.
+CREATE TABLE IF NOT EXISTS `characters` (
+  `character_id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `aliases` text DEFAULT NULL,
+  `description` text DEFAULT NULL,
+  PRIMARY KEY (`character_id`),
+  UNIQUE KEY `name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_uca1400_ai_ci;
+
+-- Timestamps on volumes, so the generator's watch mode notices edited volumes
+ALTER TABLE `volumes`
+  ADD COLUMN `created_at` timestamp NULL DEFAULT current_timestamp(),
+  ADD COLUMN `updated_at` timestamp NULL DEFAULT current_timestamp() ON UPDATE current_timestamp();
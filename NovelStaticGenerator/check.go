@@ -0,0 +1,42 @@
+package main
+
+import (
+	"NovelStaticGenerator/internal/config"
+	"NovelStaticGenerator/internal/fixtures"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// runCheckTemplates renders every template of the configured theme against
+// fixture data and reports the failures. It needs neither the database nor an
+// output directory, so it is safe to run while editing a theme; it exits with
+// status 1 when any template fails.
+func runCheckTemplates(cfg *config.Config) {
+	chapterVariants, err := loadVariants(cfg)
+	if err != nil {
+		fatal("error loading variants", err)
+	}
+	novels, chapters := fixtures.Novels()
+	gen, err := newGenerator(cfg, novels, chapters)
+	if err != nil {
+		fatal("error setting up the generator", err, "theme", cfg.Theme)
+	}
+	gen.Pages = fixtures.Pages()
+	gen.Characters = fixtures.Characters()
+	gen.Variants = chapterVariants
+
+	problems, err := gen.CheckTemplates()
+	if err != nil {
+		fatal("error preparing fixtures", err)
+	}
+	if len(problems) == 0 {
+		slog.Info("all templates rendered without errors", "templates", len(gen.Templates), "theme", cfg.Theme)
+		return
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stdout, problem)
+	}
+	slog.Error("template check failed", "problems", len(problems), "theme", cfg.Theme)
+	os.Exit(1)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"NovelStaticGenerator/internal/config"
+	"NovelStaticGenerator/internal/database"
+	"NovelStaticGenerator/internal/formatter"
+	"NovelStaticGenerator/internal/importer"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/templatefuncs"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// novelStatuses are the values of the novels.status column.
+var novelStatuses = []string{"ongoing", "finished", "hiatus"}
+
+// runImport splits a novel's text file into volumes and chapters and writes
+// them to the database, replacing the NovelExtractor and NovelPublisher pair
+// for files at hand. With -preview it prints what it found instead and needs
+// no database.
+func runImport(cfg *config.Config) {
+	c := cfg.Import
+	if c.File == "" || c.Novel == "" {
+		fatal("invalid configuration", errors.New("-file and -novel are required"))
+	}
+	if !slices.Contains(novelStatuses, c.Status) {
+		fatal("invalid configuration", fmt.Errorf("unknown novel status %q (known: %s)", c.Status, strings.Join(novelStatuses, ", ")))
+	}
+	opts := importer.Options{ChapterSplitter: c.ChapterSplitter, StartChapterAt: c.StartChapterAt, StartVolumeAt: c.StartVolumeAt}
+	var err error
+	if opts.VolumePattern, err = regexp.Compile(c.VolumePattern); err != nil {
+		fatal("invalid volume pattern", err)
+	}
+	if opts.ChapterEnding, err = regexp.Compile(c.ChapterEnding); err != nil {
+		fatal("invalid chapter ending pattern", err)
+	}
+
+	file, err := os.Open(c.File)
+	if err != nil {
+		fatal("error opening novel file", err)
+	}
+	volumes, err := importer.Split(file, opts)
+	file.Close()
+	if err != nil {
+		fatal("error splitting novel file", err, "file", c.File)
+	}
+	if len(volumes) == 0 {
+		fatal("no chapters found", errors.New("no line contains the chapter splitter text"), "file", c.File, "splitter", c.ChapterSplitter)
+	}
+
+	if c.Preview {
+		printImportPreview(os.Stdout, c.Novel, volumes)
+		return
+	}
+
+	if err := cfg.RequireDatabase(); err != nil {
+		fatal("invalid configuration", err)
+	}
+	db, err := database.ConnectDB(cfg.DSN())
+	if err != nil {
+		fatal("error connecting to database", err)
+	}
+	defer db.Close()
+
+	// Highlight the same names the generator does when it formats content_plain
+	characters, err := database.FetchAllCharacters(db)
+	if err != nil {
+		fatal("error fetching characters", err)
+	}
+	var names []string
+	for _, character := range characters {
+		names = append(names, character.Name)
+		names = append(names, character.Aliases...)
+	}
+	if len(names) == 0 {
+		names = formatter.DefaultNames
+	}
+
+	novel, chapters := importRows(c, volumes, formatter.New(names))
+	if err := database.ImportNovel(db, novel, chapters); err != nil {
+		fatal("error importing novel", err, "file", c.File)
+	}
+}
+
+// importRows turns the volumes found in the file into the rows to insert. The
+// HTML columns are formatted from the text like the publisher's HtmlConverter
+// did.
+func importRows(c config.ImportConfig, volumes []*importer.Volume, f *formatter.Formatter) (*models.Novel, []*models.Chapter) {
+	novel := &models.Novel{Name: c.Novel, Author: c.Author, Status: c.Status, Description: c.Description, Lang: c.Lang}
+	var chapters []*models.Chapter
+	for _, v := range volumes {
+		title := v.Title
+		if title == "" {
+			title = fmt.Sprintf("Volume %d", v.Number)
+		}
+		novel.Volumes = append(novel.Volumes, &models.Volume{Number: v.Number, Title: title})
+		for _, ch := range v.Chapters {
+			chapters = append(chapters, &models.Chapter{
+				NovelName:     c.Novel,
+				VolumeNumber:  v.Number,
+				ChapterNumber: ch.Number,
+				Plain:         ch.Text,
+				Content: map[string]template.HTML{
+					"content_html":  f.Format(ch.Text, formatter.Plain),
+					"content_bulma": f.Format(ch.Text, formatter.Styled),
+				},
+			})
+		}
+	}
+	return novel, chapters
+}
+
+// printImportPreview writes the volumes and chapters found in the file, with
+// the lines each spans and its first line of text, so the markers can be
+// checked before anything is written.
+func printImportPreview(w io.Writer, novel string, volumes []*importer.Volume) {
+	fmt.Fprintf(w, "%s: %d volumes, %d chapters\n", novel, len(volumes), importer.Count(volumes))
+	unterminated := 0
+	for _, v := range volumes {
+		fmt.Fprintln(w)
+		switch {
+		case v.Title == "":
+			fmt.Fprintf(w, "Volume %d (before any volume line)\n", v.Number)
+		default:
+			fmt.Fprintf(w, "Volume %d: %s (line %d)\n", v.Number, v.Title, v.Line)
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, ch := range v.Chapters {
+			note := ""
+			if ch.Unterminated {
+				note = "  [no ending line]"
+				unterminated++
+			}
+			_, body, _ := strings.Cut(ch.Text, "\n") // Skip the "Chapter N" line
+			first, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+			fmt.Fprintf(tw, "  Chapter %d\tlines %d-%d\t%d words\t%s%s\n",
+				ch.Number, ch.StartLine, ch.EndLine, templatefuncs.WordCount(body), templatefuncs.Truncate(60, first), note)
+		}
+		tw.Flush()
+	}
+	if unterminated > 0 {
+		slog.Warn("some chapters have no ending line; check the chapter ending pattern", "chapters", unterminated)
+	}
+}
@@ -0,0 +1,134 @@
+// Package anchors gives the headings and scene breaks of chapter HTML stable
+// ids, so readers can link to a part of a chapter, and lists them as the
+// chapter's table of contents.
+package anchors
+
+import (
+	"NovelStaticGenerator/internal/htmltoken"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/utils"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// separatorRegex matches the text of a paragraph that only separates scenes,
+// such as "* * *", "###" or "⁂".
+var separatorRegex = regexp.MustCompile(`^(?:(?:[*#~•·=_—–-]\s*){3,}|⁂)$`)
+
+// Add returns content with an id on every heading and scene break that has
+// none, and the table of contents: headings in order, and scene breaks (an
+// <hr> or a paragraph like "* * *") numbered by the scene they start. Heading
+// ids are slugs of their text ("the-duel", "the-duel-2" for a repeat, "section"
+// for text a slug keeps nothing of), scene break ids "scene-2", "scene-3"…, so
+// they stay the same across builds as long as the text does. Ids already in
+// the content are kept and never reused.
+func Add(content template.HTML) (template.HTML, []*models.TOCEntry) {
+	src := string(content)
+	used := existingIDs(src)
+
+	var (
+		pieces []string
+		toc    []*models.TOCEntry
+		scene  = 1
+
+		open     *htmltoken.Token // Heading or paragraph whose text is being read
+		openAt   int              // Index of its start tag in pieces
+		openText strings.Builder
+	)
+	// finish gives the element started at openAt its id (unless it has one)
+	// and returns the id.
+	finish := func(tok *htmltoken.Token, at int, id string) string {
+		if existing, ok := tok.Attr("id"); ok && existing != "" {
+			return existing
+		}
+		id = unique(id, used)
+		tok.SetAttr("id", id)
+		pieces[at] = tok.TagString()
+		return id
+	}
+
+	// closeOpen ends the open heading or paragraph, adding it to the table of
+	// contents if it is a heading or a scene break.
+	closeOpen := func() {
+		text := strings.Join(strings.Fields(html.UnescapeString(openText.String())), " ")
+		if level := headingLevel(open.Data); level > 0 {
+			slug := utils.Slugify(text)
+			if slug == utils.UntitledSlug {
+				slug = "section" // No text, or none a slug keeps, such as Japanese
+			}
+			toc = append(toc, &models.TOCEntry{ID: finish(open, openAt, slug), Title: text, Level: level})
+		} else if separatorRegex.MatchString(text) {
+			scene++
+			toc = append(toc, &models.TOCEntry{ID: finish(open, openAt, fmt.Sprintf("scene-%d", scene)), Scene: scene})
+		}
+		open = nil
+	}
+
+	z := htmltoken.New(src)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			break
+		}
+		pieces = append(pieces, tok.Raw)
+		if open != nil && open.Data == "p" && tok.Type == htmltoken.StartTag && (tok.Data == "p" || tok.Data == "hr" || headingLevel(tok.Data) > 0) {
+			closeOpen() // The next block ends an unclosed <p>, as it does in browsers
+		}
+		switch {
+		case tok.Type == htmltoken.StartTag && open == nil && (headingLevel(tok.Data) > 0 || tok.Data == "p") && !tok.SelfClosing:
+			open, openAt = &tok, len(pieces)-1
+			openText.Reset()
+
+		case tok.Type == htmltoken.Text && open != nil:
+			openText.WriteString(tok.Data)
+
+		case tok.Type == htmltoken.EndTag && open != nil && tok.Data == open.Data:
+			closeOpen()
+
+		case tok.Type == htmltoken.StartTag && tok.Data == "hr" && open == nil:
+			scene++
+			toc = append(toc, &models.TOCEntry{ID: finish(&tok, len(pieces)-1, fmt.Sprintf("scene-%d", scene)), Scene: scene})
+		}
+	}
+	if open != nil {
+		closeOpen()
+	}
+	return template.HTML(strings.Join(pieces, "")), toc
+}
+
+// headingLevel returns 1–6 for h1–h6 and 0 for any other tag.
+func headingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && '1' <= tag[1] && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
+
+// existingIDs collects the ids already used in src.
+func existingIDs(src string) map[string]bool {
+	used := make(map[string]bool)
+	z := htmltoken.New(src)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			return used
+		}
+		if id, ok := tok.Attr("id"); ok && tok.Type == htmltoken.StartTag {
+			used[id] = true
+		}
+	}
+}
+
+// unique returns id, or id with the first free "-2", "-3"… suffix, and marks
+// the result used.
+func unique(id string, used map[string]bool) string {
+	candidate := id
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", id, n)
+	}
+	used[candidate] = true
+	return candidate
+}
@@ -0,0 +1,83 @@
+package anchors
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+		toc            []string // ids, in order
+	}{
+		{"headings",
+			`<h2>The Duel</h2><p>Text.</p><h3>An &amp; Interlude</h3>`,
+			`<h2 id="the-duel">The Duel</h2><p>Text.</p><h3 id="an-interlude">An &amp; Interlude</h3>`,
+			[]string{"the-duel", "an-interlude"}},
+		{"repeated headings",
+			`<h3>Interlude</h3><h3>Interlude</h3><h3>Interlude</h3>`,
+			`<h3 id="interlude">Interlude</h3><h3 id="interlude-2">Interlude</h3><h3 id="interlude-3">Interlude</h3>`,
+			[]string{"interlude", "interlude-2", "interlude-3"}},
+		{"non-Latin and empty headings",
+			`<h2>決闘</h2><h2>Поединок</h2><h2></h2><h2>Café</h2>`,
+			`<h2 id="section">決闘</h2><h2 id="section-2">Поединок</h2><h2 id="section-3"></h2><h2 id="cafe">Café</h2>`,
+			[]string{"section", "section-2", "section-3", "cafe"}},
+		{"ids in the content are kept and not reused",
+			`<h2>Duel</h2><h2 id="own">Duel</h2><p id="duel">x</p><p id="scene-2">* * *</p><hr>`,
+			`<h2 id="duel-2">Duel</h2><h2 id="own">Duel</h2><p id="duel">x</p><p id="scene-2">* * *</p><hr id="scene-3" />`,
+			[]string{"duel-2", "own", "scene-2", "scene-3"}},
+		{"scene breaks",
+			`<p>One.</p><hr><p>Two.</p><p>* * *</p><p>Three.</p><p> ### </p><p>⁂</p><p>**</p><hr/>`,
+			`<p>One.</p><hr id="scene-2" /><p>Two.</p><p id="scene-3">* * *</p><p>Three.</p><p id="scene-4"> ### </p><p id="scene-5">⁂</p><p>**</p><hr id="scene-6" />`,
+			[]string{"scene-2", "scene-3", "scene-4", "scene-5", "scene-6"}},
+		{"scene break text inside other elements",
+			`<p><em>* * *</em></p><blockquote>* * *</blockquote>`,
+			`<p id="scene-2"><em>* * *</em></p><blockquote>* * *</blockquote>`,
+			[]string{"scene-2"}},
+		{"unclosed paragraphs",
+			`<p>One.<p>* * *<h2>Duel</h2><p>Two.<hr><p>~~~`,
+			`<p>One.<p id="scene-2">* * *<h2 id="duel">Duel</h2><p>Two.<hr id="scene-3" /><p id="scene-4">~~~`,
+			[]string{"scene-2", "duel", "scene-3", "scene-4"}},
+		{"markup in headings",
+			`<h2 class="title">The <em>Long</em> Duel</h2>`,
+			`<h2 class="title" id="the-long-duel">The <em>Long</em> Duel</h2>`,
+			[]string{"the-long-duel"}},
+		{"no headings", `<p>Just text.</p>`, `<p>Just text.</p>`, nil},
+	}
+	for _, tt := range tests {
+		got, toc := Add(template.HTML(tt.in))
+		if string(got) != tt.want {
+			t.Errorf("%s: Add(%q)\n got %q\nwant %q", tt.name, tt.in, got, tt.want)
+		}
+		var ids []string
+		for _, entry := range toc {
+			ids = append(ids, entry.ID)
+		}
+		if len(ids) != len(tt.toc) {
+			t.Errorf("%s: toc ids = %q, want %q", tt.name, ids, tt.toc)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.toc[i] {
+				t.Errorf("%s: toc ids = %q, want %q", tt.name, ids, tt.toc)
+				break
+			}
+		}
+	}
+}
+
+func TestAddTOC(t *testing.T) {
+	_, toc := Add(`<h2>The  Duel</h2><p>* * *</p><h4>After &amp; <b>more</b></h4><hr>`)
+	want := []struct {
+		title        string
+		level, scene int
+	}{{"The Duel", 2, 0}, {"", 0, 2}, {"After & more", 4, 0}, {"", 0, 3}}
+	if len(toc) != len(want) {
+		t.Fatalf("toc has %d entries, want %d", len(toc), len(want))
+	}
+	for i, w := range want {
+		if e := toc[i]; e.Title != w.title || e.Level != w.level || e.Scene != w.scene {
+			t.Errorf("entry %d = %+v, want title %q, level %d, scene %d", i, e, w.title, w.level, w.scene)
+		}
+	}
+}
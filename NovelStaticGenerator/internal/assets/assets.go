@@ -0,0 +1,180 @@
+package assets
+
+import (
+	"NovelStaticGenerator/internal/urls"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hashLength is the number of hex digits of the content hash put into
+// fingerprinted filenames (css/bulma.css becomes css/bulma.3f9a1c2b.css).
+const hashLength = 8
+
+// Options selects the processing applied to the theme's static files.
+type Options struct {
+	Minify      bool // Minify .css and .js files
+	Fingerprint bool // Put a content hash into the names of .css and .js files
+
+	// Keep, when set, purges stylesheets of the rules needing a class it
+	// rejects (see PurgeCSS and KeepClasses).
+	Keep func(class string) bool
+}
+
+// Asset is one processed static file.
+type Asset struct {
+	Source    string // Path under static/, e.g. "css/bulma.css"
+	Path      string // Output path relative to the site root, e.g. "css/bulma.3f9a1c2b.css"
+	Integrity string // Subresource Integrity value of Data, "sha384-…"
+	Data      []byte
+}
+
+// Manifest maps the theme's static files to their processed output.
+type Manifest struct {
+	assets map[string]*Asset
+	static fs.FS   // Source files, kept for Purged
+	opts   Options // Options the assets were built with
+}
+
+// Build reads every file of static and processes it: stylesheets and scripts
+// are purged, minified and fingerprinted according to opts, anything else
+// (images, fonts) is kept byte for byte under its own name, since stylesheets
+// may refer to it by a relative URL.
+func Build(static fs.FS, opts Options) (*Manifest, error) {
+	start := time.Now()
+	m := &Manifest{assets: make(map[string]*Asset), static: static, opts: opts}
+	var before, after int
+	err := fs.WalkDir(static, ".", func(srcPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %q: %w", srcPath, err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(static, srcPath)
+		if err != nil {
+			return fmt.Errorf("could not read static asset %q: %w", srcPath, err)
+		}
+		before += len(data)
+
+		asset := &Asset{Source: srcPath, Path: srcPath}
+		ext := path.Ext(srcPath)
+		if opts.Keep != nil && ext == ".css" {
+			data = PurgeCSS(data, opts.Keep)
+		}
+		if opts.Minify {
+			switch ext {
+			case ".css":
+				data = MinifyCSS(data)
+			case ".js":
+				data = MinifyJS(data)
+			}
+		}
+		if opts.Fingerprint && (ext == ".css" || ext == ".js") {
+			sum := sha256.Sum256(data)
+			asset.Path = strings.TrimSuffix(srcPath, ext) + "." + hex.EncodeToString(sum[:])[:hashLength] + ext
+		}
+		sri := sha512.Sum384(data)
+		asset.Integrity = "sha384-" + base64.StdEncoding.EncodeToString(sri[:])
+		asset.Data = data
+		after += len(data)
+
+		m.assets[srcPath] = asset
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("processed static assets", "files", len(m.assets), "bytes_before", before, "bytes_after", after, "duration", time.Since(start))
+	return m, nil
+}
+
+// Purged rebuilds the manifest with its stylesheets purged of the rules that
+// need a class keep rejects.
+func (m *Manifest) Purged(keep func(class string) bool) (*Manifest, error) {
+	opts := m.opts
+	opts.Keep = keep
+	purged, err := Build(m.static, opts)
+	if err != nil {
+		return nil, err
+	}
+	for source, asset := range purged.assets {
+		if path.Ext(source) == ".css" {
+			slog.Info("purged unused CSS", "file", source, "bytes_before", len(m.assets[source].Data), "bytes_after", len(asset.Data))
+		}
+	}
+	return purged, nil
+}
+
+// KeepClasses returns a Keep function accepting the classes in used and those
+// matching safelist, whose entries are class names or prefixes ending in "*"
+// (e.g. "is-active", "has-text-*") for classes only added by scripts.
+func KeepClasses(used map[string]bool, safelist []string) func(string) bool {
+	return func(class string) bool {
+		if used[class] {
+			return true
+		}
+		for _, pattern := range safelist {
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(class, prefix) || pattern == class {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Assets returns every asset sorted by source path.
+func (m *Manifest) Assets() []*Asset {
+	if m == nil {
+		return nil
+	}
+	list := make([]*Asset, 0, len(m.assets))
+	for _, asset := range m.assets {
+		list = append(list, asset)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Source < list[j].Source })
+	return list
+}
+
+// Lookup returns the asset built from source (a path under static/).
+func (m *Manifest) Lookup(source string) (*Asset, bool) {
+	if m == nil {
+		return nil, false
+	}
+	asset, ok := m.assets[strings.TrimPrefix(source, "/")]
+	return asset, ok
+}
+
+// FuncMap returns the asset helpers for templates, bound to the page written
+// at pagePath:
+//
+//	<link rel="stylesheet" href="{{ asset "css/bulma.css" }}" integrity="{{ integrity "css/bulma.css" }}" crossorigin="anonymous">
+//
+// Both fail on a file the theme does not have, so typos show up as template errors.
+func (m *Manifest) FuncMap(b *urls.Builder, pagePath string) template.FuncMap {
+	return template.FuncMap{
+		"asset": func(source string) (string, error) {
+			asset, ok := m.Lookup(source)
+			if !ok {
+				return "", fmt.Errorf("asset: theme has no static file %q", source)
+			}
+			return b.RelURL(pagePath, asset.Path), nil
+		},
+		"integrity": func(source string) (string, error) {
+			asset, ok := m.Lookup(source)
+			if !ok {
+				return "", fmt.Errorf("integrity: theme has no static file %q", source)
+			}
+			return asset.Integrity, nil
+		},
+	}
+}
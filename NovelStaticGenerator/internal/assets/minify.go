@@ -0,0 +1,237 @@
+package assets
+
+import (
+	"bytes"
+	"strings"
+)
+
+// The minifiers below are deliberately conservative: they only drop comments
+// and whitespace, never rewrite values, so the output behaves exactly like the
+// input. Strings, escapes and /*! license comments are kept as they are.
+
+// cssTight lists the characters around which CSS needs no whitespace. ':' is
+// not among them because "a :hover" and "a:hover" are different selectors, nor
+// are '+' and '-', which need spaces inside calc().
+const cssTight = "{};,>"
+
+// MinifyCSS removes comments and redundant whitespace from a stylesheet.
+func MinifyCSS(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	pendingSpace := false
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			stop := len(src)
+			if end := bytes.Index(src[i+2:], []byte("*/")); end >= 0 {
+				stop = i + 2 + end + 2
+			}
+			if comment := src[i:stop]; bytes.HasPrefix(comment, []byte("/*!")) {
+				flushSpace(&out, &pendingSpace, cssTight)
+				out.Write(comment)
+				out.WriteByte('\n')
+			} else {
+				pendingSpace = true // A comment separates tokens like whitespace does
+			}
+			i = stop - 1
+
+		case c == '"' || c == '\'':
+			flushSpace(&out, &pendingSpace, cssTight)
+			i = copyString(&out, src, i)
+
+		case c == '\\' && i+1 < len(src):
+			flushSpace(&out, &pendingSpace, cssTight)
+			out.WriteByte(c)
+			out.WriteByte(src[i+1])
+			i++
+
+		case isSpace(c):
+			pendingSpace = true
+
+		case strings.IndexByte(cssTight, c) >= 0:
+			pendingSpace = false
+			if c == '}' {
+				// The last declaration of a block needs no semicolon
+				if b := out.Bytes(); len(b) > 0 && b[len(b)-1] == ';' {
+					out.Truncate(out.Len() - 1)
+				}
+			}
+			out.WriteByte(c)
+
+		default:
+			flushSpace(&out, &pendingSpace, cssTight)
+			out.WriteByte(c)
+		}
+	}
+	return bytes.TrimSpace(out.Bytes())
+}
+
+// jsRegexPrefix lists the characters after which a '/' starts a regular
+// expression literal rather than a division.
+const jsRegexPrefix = "(,=:[!&|?{};+-*%<>~^"
+
+// jsRegexKeywords lists the keywords after which a '/' starts a regular
+// expression literal, as in "return /a  b/.test(x)".
+var jsRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true, "in": true, "of": true,
+	"void": true, "throw": true, "new": true, "delete": true, "instanceof": true, "yield": true, "await": true,
+}
+
+// MinifyJS removes comments, indentation and blank lines from a script. Line
+// breaks are kept so automatic semicolon insertion keeps working.
+func MinifyJS(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	lastSignificant := byte('\n')
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(src)
+				break
+			}
+			if bytes.HasPrefix(src[i:], []byte("/*!")) {
+				out.Write(src[i : i+2+end+2])
+			}
+			i += 2 + end + 1
+
+		case c == '/' && startsRegex(out.Bytes(), lastSignificant):
+			i = copyRegex(&out, src, i)
+			lastSignificant = '/'
+
+		case c == '"' || c == '\'' || c == '`':
+			i = copyString(&out, src, i)
+			lastSignificant = c
+
+		case c == '\n' || c == '\r':
+			trimTrailingSpace(&out)
+			if b := out.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+				out.WriteByte('\n')
+			}
+			lastSignificant = '\n'
+
+		case isSpace(c):
+			// Indentation is dropped; runs of inner spaces become one
+			if b := out.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' && !isSpace(b[len(b)-1]) {
+				out.WriteByte(' ')
+			}
+
+		default:
+			out.WriteByte(c)
+			lastSignificant = c
+		}
+	}
+	trimTrailingSpace(&out)
+	return bytes.TrimSpace(out.Bytes())
+}
+
+// startsRegex reports whether a '/' after out, whose last significant
+// character is last, starts a regular expression literal.
+func startsRegex(out []byte, last byte) bool {
+	if last == '\n' || strings.IndexByte(jsRegexPrefix, last) >= 0 {
+		return true
+	}
+	return jsRegexKeywords[lastWord(out)]
+}
+
+// lastWord returns the identifier at the end of out, leaving aside spaces, or
+// "" if there is none or it is a property name such as the one in "a.return".
+func lastWord(out []byte) string {
+	out = bytes.TrimRight(out, " \t")
+	start := len(out)
+	for start > 0 && isIdentByte(out[start-1]) {
+		start--
+	}
+	if start > 0 && out[start-1] == '.' {
+		return ""
+	}
+	return string(out[start:])
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// copyString copies the quoted string starting at src[start] (including its
+// quotes and escapes) and returns the index of its closing quote.
+func copyString(out *bytes.Buffer, src []byte, start int) int {
+	quote := src[start]
+	out.WriteByte(quote)
+	for i := start + 1; i < len(src); i++ {
+		out.WriteByte(src[i])
+		switch src[i] {
+		case '\\':
+			if i+1 < len(src) {
+				i++
+				out.WriteByte(src[i])
+			}
+		case quote:
+			return i
+		}
+	}
+	return len(src) - 1
+}
+
+// copyRegex copies the regular expression literal starting at src[start],
+// including character classes that may contain '/', and returns the index of
+// its closing slash.
+func copyRegex(out *bytes.Buffer, src []byte, start int) int {
+	out.WriteByte('/')
+	inClass := false
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		out.WriteByte(c)
+		switch {
+		case c == '\\' && i+1 < len(src):
+			i++
+			out.WriteByte(src[i])
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			return i
+		case c == '\n':
+			return i // Not a regex after all; stop at the line end
+		}
+	}
+	return len(src) - 1
+}
+
+// flushSpace writes a pending space unless the previous byte makes it redundant.
+func flushSpace(out *bytes.Buffer, pending *bool, tight string) {
+	if !*pending {
+		return
+	}
+	*pending = false
+	b := out.Bytes()
+	if len(b) == 0 || b[len(b)-1] == '\n' || strings.IndexByte(tight, b[len(b)-1]) >= 0 {
+		return
+	}
+	out.WriteByte(' ')
+}
+
+// trimTrailingSpace drops spaces and tabs at the end of out.
+func trimTrailingSpace(out *bytes.Buffer) {
+	b := out.Bytes()
+	n := len(b)
+	for n > 0 && (b[n-1] == ' ' || b[n-1] == '\t') {
+		n--
+	}
+	out.Truncate(n)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
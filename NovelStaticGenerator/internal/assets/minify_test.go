@@ -0,0 +1,61 @@
+package assets
+
+import "testing"
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"whitespace and comments", "a  {\n  color : red ;\n  /* note */ margin: 0 auto;\n}\n", "a{color : red;margin: 0 auto}"},
+		{"descendant and pseudo selectors", "nav a :hover, nav   a:hover > b {x: y}", "nav a :hover,nav a:hover>b{x: y}"},
+		{"calc keeps its spaces", "a { width: calc(100%  -  2px); }", "a{width: calc(100% - 2px)}"},
+		{"strings are kept", `a::before { content: "  /* not a comment */  "; }`, `a::before{content: "  /* not a comment */  "}`},
+		{"escapes are kept", `.w-1\/2 { x: 1 } .a\ b{}`, `.w-1\/2{x: 1}.a\ b{}`},
+		{"license comments are kept", "/*! Bulma | MIT */\na{}", "/*! Bulma | MIT */\na{}"},
+		{"comment between tokens", "a/**/b{}", "a b{}"},
+		{"at-rules", "@media (min-width: 768px) {\n  .a { x: 1; }\n}", "@media (min-width: 768px){.a{x: 1}}"},
+		{"unterminated comment", "a{} /* b", "a{}"},
+	}
+	for _, tt := range tests {
+		if got := string(MinifyCSS([]byte(tt.in))); got != tt.want {
+			t.Errorf("%s: MinifyCSS(%q)\n got %q\nwant %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMinifyJS(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"indentation and blank lines", "function f() {\n    var a = 1;\n\n    return a;\n}\n", "function f() {\nvar a = 1;\nreturn a;\n}"},
+		{"comments", "a(); // call\n/* block\n comment */b();", "a();\nb();"},
+		{"license comments are kept", "/*! MIT */\na();", "/*! MIT */\na();"},
+		{"strings are kept", "s = \"a  // b\" + 'c  /* d */' + `e\n  f`;", "s = \"a  // b\" + 'c  /* d */' + `e\n  f`;"},
+		{"escaped quotes", `s = "a \"  b";`, `s = "a \"  b";`},
+		{"division", "x = a  /  b / c;", "x = a / b / c;"},
+		{"regex after an operator", "x = /a  b/g.test(s);", "x = /a  b/g.test(s);"},
+		{"regex after a paren", "s.replace(/  +/g, ' ');", "s.replace(/  +/g, ' ');"},
+		{"regex with a slash in a class", "x = /[/  ]+/;", "x = /[/  ]+/;"},
+		{"regex with an escaped slash", `x = /a\/  b/;`, `x = /a\/  b/;`},
+		{"regex with comment-like content", "x = /a*  b/;", "x = /a*  b/;"},
+		{"regex at a line start", "/a  b/.test(s)", "/a  b/.test(s)"},
+		{"regex after return", "return /a  b/.test(x)", "return /a  b/.test(x)"},
+		{"regex after typeof", "typeof /a  b/", "typeof /a  b/"},
+		{"regex after case", "case /a  b/.source:", "case /a  b/.source:"},
+		{"regex after do", "do /a  b/.exec(s); while (0)", "do /a  b/.exec(s); while (0)"},
+		{"regex after else", "if (x) y(); else /a  b/.exec(s)", "if (x) y(); else /a  b/.exec(s)"},
+		{"regex after in", "x = k in /a  b/", "x = k in /a  b/"},
+		{"regex after of", "for (const m of /a  b/.exec(s)) {}", "for (const m of /a  b/.exec(s)) {}"},
+		{"regex after void", "void /a  b/", "void /a  b/"},
+		{"regex after throw", "throw /a  b/", "throw /a  b/"},
+		{"regex after new", "new /a  b/.constructor()", "new /a  b/.constructor()"},
+		{"division after an identifier ending like a keyword", "x = ifdo  /  2 / return_ / 3", "x = ifdo / 2 / return_ / 3"},
+		{"division after a property named like a keyword", "x = a.return  /  2 / b.in / 3", "x = a.return / 2 / b.in / 3"},
+		{"division after a closing paren", "x = (a + b)  /  2 / c", "x = (a + b) / 2 / c"},
+	}
+	for _, tt := range tests {
+		if got := string(MinifyJS([]byte(tt.in))); got != tt.want {
+			t.Errorf("%s: MinifyJS(%q)\n got %q\nwant %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
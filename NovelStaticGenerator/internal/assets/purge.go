@@ -0,0 +1,281 @@
+package assets
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// classAttrRegex finds class attributes in generated HTML, quoted or not.
+var classAttrRegex = regexp.MustCompile(`(?i)\sclass\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// CollectClasses adds the class names used by the HTML page to used.
+func CollectClasses(page []byte, used map[string]bool) {
+	for _, m := range classAttrRegex.FindAllSubmatch(page, -1) {
+		value := m[1]
+		if value == nil {
+			value = m[2]
+		}
+		if value == nil {
+			value = m[3]
+		}
+		for _, class := range strings.Fields(string(value)) {
+			used[class] = true
+		}
+	}
+}
+
+// groupingAtRules hold ordinary rules in their block and are purged recursively.
+// Every other at-rule (@font-face, @keyframes, @page…) is kept as it is.
+var groupingAtRules = []string{"@media", "@supports", "@layer", "@container", "@document"}
+
+// PurgeCSS removes the rules of a stylesheet that cannot match the page: a
+// selector is dropped when it requires a class keep rejects, and a rule is
+// dropped when all its selectors are. The test is conservative — selectors
+// without classes (element, id and attribute selectors) always stay, and classes
+// inside :not(), :is() and other functional pseudo-classes are ignored.
+func PurgeCSS(css []byte, keep func(class string) bool) []byte {
+	var out bytes.Buffer
+	purgeBlock(&out, css, keep)
+	return out.Bytes()
+}
+
+// purgeBlock writes the kept statements of a list of rules to out.
+func purgeBlock(out *bytes.Buffer, css []byte, keep func(string) bool) {
+	for i := 0; i < len(css); {
+		// Whitespace and comments between statements; license comments stay
+		for i < len(css) && isSpace(css[i]) {
+			i++
+		}
+		if bytes.HasPrefix(css[i:], []byte("/*")) {
+			end := skipComment(css, i)
+			if bytes.HasPrefix(css[i:], []byte("/*!")) {
+				out.Write(css[i:end])
+				out.WriteByte('\n')
+			}
+			i = end
+			continue
+		}
+		if i >= len(css) {
+			return
+		}
+
+		stop := scanUntil(css, i, "{;}")
+		prelude := bytes.TrimSpace(css[i:stop])
+		if stop >= len(css) || css[stop] != '{' {
+			// A statement such as @charset or @import, or a stray '}'
+			if len(prelude) > 0 {
+				out.Write(prelude)
+				out.WriteString(";\n")
+			}
+			i = stop + 1
+			continue
+		}
+
+		blockEnd := matchingBrace(css, stop)
+		body := css[stop+1 : min(blockEnd, len(css))]
+		i = blockEnd + 1
+
+		if len(prelude) == 0 {
+			continue // A block without a selector, which browsers ignore
+		}
+		if prelude[0] == '@' {
+			if !isGroupingAtRule(prelude) {
+				out.Write(prelude)
+				out.WriteByte('{')
+				out.Write(body)
+				out.WriteString("}\n")
+				continue
+			}
+			var inner bytes.Buffer
+			purgeBlock(&inner, body, keep)
+			if len(bytes.TrimSpace(inner.Bytes())) == 0 {
+				continue
+			}
+			out.Write(prelude)
+			out.WriteString("{\n")
+			out.Write(inner.Bytes())
+			out.WriteString("}\n")
+			continue
+		}
+
+		var kept []string
+		for _, selector := range splitSelectors(prelude) {
+			if selectorMatches(selector, keep) {
+				kept = append(kept, selector)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		out.WriteString(strings.Join(kept, ", "))
+		out.WriteByte('{')
+		out.Write(body)
+		out.WriteString("}\n")
+	}
+}
+
+// isGroupingAtRule reports whether prelude starts a grouping at-rule.
+func isGroupingAtRule(prelude []byte) bool {
+	name := prelude
+	if i := bytes.IndexFunc(prelude, func(r rune) bool { return r == ' ' || r == '(' || r == '\n' || r == '\t' }); i > 0 {
+		name = prelude[:i]
+	}
+	for _, rule := range groupingAtRules {
+		if strings.EqualFold(string(name), rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanUntil returns the index of the first byte of stops in css at or after
+// start that is outside strings, comments, escapes and parentheses.
+func scanUntil(css []byte, start int, stops string) int {
+	depth := 0
+	for i := start; i < len(css); i++ {
+		switch c := css[i]; {
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			i = skipString(css, i)
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			i = skipComment(css, i) - 1
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0 && strings.IndexByte(stops, c) >= 0:
+			return i
+		}
+	}
+	return len(css)
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open.
+func matchingBrace(css []byte, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch c := css[i]; {
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			i = skipString(css, i)
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			i = skipComment(css, i) - 1
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(css)
+}
+
+// skipString returns the index of the quote closing the string at start.
+func skipString(css []byte, start int) int {
+	quote := css[start]
+	for i := start + 1; i < len(css); i++ {
+		switch css[i] {
+		case '\\':
+			i++
+		case quote, '\n':
+			return i
+		}
+	}
+	return len(css)
+}
+
+// skipComment returns the index just past the comment starting at start.
+func skipComment(css []byte, start int) int {
+	if end := bytes.Index(css[start+2:], []byte("*/")); end >= 0 {
+		return start + 2 + end + 2
+	}
+	return len(css)
+}
+
+// splitSelectors splits a selector list at its top-level commas.
+func splitSelectors(prelude []byte) []string {
+	var selectors []string
+	for start := 0; start <= len(prelude); {
+		stop := scanUntil(prelude, start, ",")
+		if selector := strings.TrimSpace(string(prelude[start:stop])); selector != "" {
+			selectors = append(selectors, selector)
+		}
+		start = stop + 1
+	}
+	return selectors
+}
+
+// selectorMatches reports whether every class the selector requires outside
+// parentheses and attribute selectors passes keep.
+func selectorMatches(selector string, keep func(string) bool) bool {
+	depth := 0
+	for i := 0; i < len(selector); i++ {
+		switch c := selector[i]; {
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			i = skipString([]byte(selector), i)
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			if depth > 0 {
+				depth--
+			}
+		case c == '.' && depth == 0:
+			class, n := readIdent(selector[i+1:])
+			if class != "" && !keep(class) {
+				return false
+			}
+			i += n
+		}
+	}
+	return true
+}
+
+// readIdent decodes the CSS identifier at the start of s (resolving escapes
+// such as "0\.5" or "\31 0") and returns it with the number of bytes consumed.
+func readIdent(s string) (string, int) {
+	var ident strings.Builder
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			j := i + 1
+			for j < len(s) && j < i+7 && isHex(s[j]) {
+				j++
+			}
+			if j > i+1 {
+				code, _ := strconv.ParseUint(s[i+1:j], 16, 32)
+				ident.WriteRune(rune(code))
+				if j < len(s) && s[j] == ' ' {
+					j++
+				}
+				i = j
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(s[i+1:])
+			ident.WriteRune(r)
+			i += 1 + size
+		case c == '-' || c == '_' || c >= 0x80 || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+			ident.WriteByte(c)
+			i++
+		default:
+			return ident.String(), i
+		}
+	}
+	return ident.String(), i
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
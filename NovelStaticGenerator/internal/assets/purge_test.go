@@ -0,0 +1,82 @@
+package assets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCollectClasses(t *testing.T) {
+	used := map[string]bool{}
+	CollectClasses([]byte(`<div class="a  b"><p CLASS='c'><span class=d>x</span><i data-class="e">class="f"</i></div>`), used)
+	want := map[string]bool{"a": true, "b": true, "c": true, "d": true}
+	if !reflect.DeepEqual(used, want) {
+		t.Errorf("used = %v, want %v", used, want)
+	}
+}
+
+func TestKeepClasses(t *testing.T) {
+	keep := KeepClasses(map[string]bool{"used": true}, []string{"is-active", "has-text-*"})
+	for class, want := range map[string]bool{
+		"used": true, "is-active": true, "has-text-centered": true,
+		"unused": false, "is-active-2": false, "has-background": false,
+	} {
+		if got := keep(class); got != want {
+			t.Errorf("keep(%q) = %v, want %v", class, got, want)
+		}
+	}
+}
+
+func TestPurgeCSS(t *testing.T) {
+	keep := KeepClasses(map[string]bool{"used": true, "w-1/2": true, "10": true}, nil)
+	tests := []struct {
+		name, in, want string
+	}{
+		{"unused rule", ".used{a:1} .unused{b:2}", ".used{a:1}\n"},
+		{"element, id and attribute selectors stay", "p{a:1} #x{b:2} [data-x]{c:3} *{d:4}", "p{a:1}\n#x{b:2}\n[data-x]{c:3}\n*{d:4}\n"},
+		{"selector lists keep the matching selectors", ".used, .unused > p, a{x:1}", ".used, a{x:1}\n"},
+		{"compound selectors need every class", ".used.unused{x:1} .used .used:hover{y:2}", ".used .used:hover{y:2}\n"},
+		{"classes in functional pseudo-classes are ignored", "p:not(.unused){x:1} :is(.a, .b){y:2}", "p:not(.unused){x:1}\n:is(.a, .b){y:2}\n"},
+		{"classes in attribute selectors are ignored", `a[href$=".pdf"]{x:1}`, `a[href$=".pdf"]{x:1}` + "\n"},
+		{"escaped class names", `.w-1\/2{x:1} .\31 0{y:2} .w-1\/3{z:3}`, `.w-1\/2{x:1}` + "\n" + `.\31 0{y:2}` + "\n"},
+		{"grouping at-rules are purged inside", "@media (min-width: 1px){.used{a:1}.unused{b:2}}", "@media (min-width: 1px){\n.used{a:1}\n}\n"},
+		{"emptied grouping at-rules are dropped", "@supports (display: grid){.unused{b:2}} .used{a:1}", ".used{a:1}\n"},
+		{"nested grouping at-rules", "@media print{@supports (x: y){.unused{a:1}.used{b:2}}}", "@media print{\n@supports (x: y){\n.used{b:2}\n}\n}\n"},
+		{"other at-rules are kept as they are", "@font-face{font-family:x}@keyframes spin{from{a:1}to{a:2}}", "@font-face{font-family:x}\n@keyframes spin{from{a:1}to{a:2}}\n"},
+		{"statements are kept", `@charset "utf-8";@import url("a.css");.unused{a:1}`, "@charset \"utf-8\";\n@import url(\"a.css\");\n"},
+		{"braces in strings and comments", `.used::before{content:"}"}/* .unused{ */.unused{a:"{"}`, `.used::before{content:"}"}` + "\n"},
+		{"license comments are kept", "/*! MIT */.unused{a:1}", "/*! MIT */\n"},
+		{"blocks without a selector are dropped", ".used{a:1}\n{b:2} @media print{{c:3}.used{d:4}}", ".used{a:1}\n@media print{\n.used{d:4}\n}\n"},
+	}
+	for _, tt := range tests {
+		if got := string(PurgeCSS([]byte(tt.in), keep)); got != tt.want {
+			t.Errorf("%s: PurgeCSS(%q)\n got %q\nwant %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuildPurged(t *testing.T) {
+	static := fstest.MapFS{
+		"css/site.css": {Data: []byte(".used {\n  color: red;\n}\n\n.unused {\n  color: blue;\n}\n@media screen {\n  .unused { x: 1; }\n  .used { y: 2; }\n}\n")},
+		"js/site.js":   {Data: []byte("x = 1;\n")},
+	}
+	m, err := Build(static, Options{Minify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	purged, err := m.Purged(KeepClasses(map[string]bool{"used": true}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	css, _ := purged.Lookup("css/site.css")
+	if got, want := string(css.Data), ".used{color: red}@media screen{.used{y: 2}}"; got != want {
+		t.Errorf("purged stylesheet = %q, want %q", got, want)
+	}
+	if js, _ := purged.Lookup("js/site.js"); string(js.Data) != "x = 1;" {
+		t.Errorf("script = %q, want it minified and not purged", js.Data)
+	}
+	if original, _ := m.Lookup("css/site.css"); !strings.Contains(string(original.Data), ".unused") {
+		t.Error("Purged changed the manifest it was called on")
+	}
+}
@@ -5,45 +5,182 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 )
 
 // Config holds application configuration.
 type Config struct {
-	DBUser     string
-	DBPassword string
-	DBHost     string
-	DBPort     string
-	DBName     string
-	OutputDir  string
+	DBUser      string
+	DBPassword  string
+	DBHost      string
+	DBPort      string
+	DBName      string
+	OutputDir   string
+	Theme       string // Theme directory layered over the built-in theme ("" for built-in only)
+	ContentDir  string // Directory of Markdown content pages (skipped if missing)
+	Permalink   string // Chapter URL pattern, e.g. "{novel}/{volume}/{chapter}/index.html"
+	PrettyURLs  bool   // Link to directories instead of their index.html
+	BaseURL     string // Public URL of the site root, e.g. "https://example.org/novels/"
+	Lang        string // Site language for UI strings, e.g. "es"; novels may set their own
+	Variants    string // JSON file defining the chapter variants ("" for plain and styled)
+	Reformat    bool   // Render every variant from content_plain instead of the stored HTML
+	Minify      bool   // Minify the theme's CSS and JS files
+	Fingerprint bool   // Put content hashes into CSS and JS filenames
+	PurgeCSS    bool   // Drop CSS rules for classes the generated pages do not use
+	Sanitize    bool   // Strip chapter HTML the variant's sanitize policy does not allow
+	Search      bool   // Write the search index and page
+	Typography  string // Comma-separated typography rules for chapter text, or "none"
+	Safelist    string // Comma-separated classes (or "prefix-*" patterns) the purge keeps
+	LogLevel    string // debug, info, warn or error
+	LogFormat   string // text or json
+
+	Import ImportConfig // Settings of the import command only
+	Serve  ServeConfig  // Settings of the serve command only
+	Watch  WatchConfig  // Settings of the watch command only
+}
+
+// WatchConfig holds the settings of the watch command.
+type WatchConfig struct {
+	Interval time.Duration // How often the database is checked for changes
+}
+
+// ServeConfig holds the settings of the serve command.
+type ServeConfig struct {
+	Addr string        // Address the preview server listens on
+	Poll time.Duration // How often the theme and content directories are checked for changes
+}
+
+// ImportConfig holds the settings of the import command. The markers default
+// to those of the C# NovelExtractor.
+type ImportConfig struct {
+	File            string // Text file of the novel
+	Novel           string // Name of the novel to create or add to
+	Author          string
+	Status          string // ongoing, finished or hiatus
+	Description     string
+	Lang            string // Language of the novel ("" for the site language)
+	VolumePattern   string // Regexp of the lines starting a volume
+	ChapterSplitter string // Text of the lines starting a chapter
+	ChapterEnding   string // Regexp of the lines ending a chapter
+	StartChapterAt  int
+	StartVolumeAt   int
+	Preview         bool // Print the detected volumes and chapters instead of writing them
 }
 
-// LoadConfig loads configuration from environment variables or command-line flags.
-// Flags take precedence over environment variables.
-func LoadConfig() (*Config, error) {
+// LoadConfig loads configuration for the given subcommand from environment
+// variables and the command-line flags in args. Flags take precedence over
+// environment variables. Commands check the settings they need afterwards with
+// RequireDatabase and RequireOutput.
+func LoadConfig(command string, args []string) (*Config, error) {
 	cfg := &Config{}
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
 
 	// Define flags
-	flag.StringVar(&cfg.DBUser, "dbuser", os.Getenv("DB_USER"), "Database username (env: DB_USER)")
-	flag.StringVar(&cfg.DBPassword, "dbpass", os.Getenv("DB_PASSWORD"), "Database password (env: DB_PASSWORD)")
-	flag.StringVar(&cfg.DBHost, "dbhost", os.Getenv("DB_HOST"), "Database host (env: DB_HOST)")
-	flag.StringVar(&cfg.DBPort, "dbport", os.Getenv("DB_PORT"), "Database port (env: DB_PORT)")
-	flag.StringVar(&cfg.DBName, "dbname", os.Getenv("DB_NAME"), "Database name (env: DB_NAME)")
-	flag.StringVar(&cfg.OutputDir, "output", os.Getenv("OUTPUT_DIR"), "Output directory for static site (env: OUTPUT_DIR)")
+	flags.StringVar(&cfg.DBUser, "dbuser", os.Getenv("DB_USER"), "Database username (env: DB_USER)")
+	flags.StringVar(&cfg.DBPassword, "dbpass", os.Getenv("DB_PASSWORD"), "Database password (env: DB_PASSWORD)")
+	flags.StringVar(&cfg.DBHost, "dbhost", os.Getenv("DB_HOST"), "Database host (env: DB_HOST)")
+	flags.StringVar(&cfg.DBPort, "dbport", os.Getenv("DB_PORT"), "Database port (env: DB_PORT)")
+	flags.StringVar(&cfg.DBName, "dbname", os.Getenv("DB_NAME"), "Database name (env: DB_NAME)")
+	flags.StringVar(&cfg.OutputDir, "output", os.Getenv("OUTPUT_DIR"), "Output directory for static site (env: OUTPUT_DIR)")
 
-	flag.Parse()
+	flags.StringVar(&cfg.Theme, "theme", os.Getenv("THEME"), "Theme directory with layouts/, partials/ and static/; missing files fall back to the built-in theme (env: THEME)")
+	flags.StringVar(&cfg.ContentDir, "content", envOrDefault("CONTENT_DIR", "content"), "Directory of Markdown content pages with front matter (env: CONTENT_DIR)")
+	flags.StringVar(&cfg.Permalink, "permalink", os.Getenv("PERMALINK"), "Chapter URL pattern with {novel}, {volume}, {chapter}, {chapter_id}, {title_slug} and {variant} placeholders (env: PERMALINK)")
+	flags.BoolVar(&cfg.PrettyURLs, "pretty-urls", os.Getenv("PRETTY_URLS") == "true", "Link to directories instead of index.html files (env: PRETTY_URLS)")
+	flags.StringVar(&cfg.BaseURL, "base-url", os.Getenv("BASE_URL"), "Public URL of the site root, used for canonical links, feeds and sitemaps (env: BASE_URL)")
+	flags.StringVar(&cfg.Lang, "lang", envOrDefault("SITE_LANG", "en"), "Site language for UI strings and <html lang>; novels with a lang column override it (env: SITE_LANG)")
+	flags.StringVar(&cfg.Variants, "variants", os.Getenv("VARIANTS"), "JSON file defining the chapter variants (name, label, content column, suffix, stylesheets, template); default plain and styled (env: VARIANTS)")
+	flags.BoolVar(&cfg.Reformat, "reformat", os.Getenv("REFORMAT") == "true", "Format chapters from content_plain with the Go formatter instead of using content_html and content_bulma (env: REFORMAT)")
+	flags.BoolVar(&cfg.Minify, "minify", os.Getenv("MINIFY") != "false", "Minify the theme's CSS and JS files (env: MINIFY)")
+	flags.BoolVar(&cfg.Fingerprint, "fingerprint", os.Getenv("FINGERPRINT") != "false", "Write CSS and JS files under content-hashed names such as bulma.3f9a1c2b.css (env: FINGERPRINT)")
+	flags.BoolVar(&cfg.Sanitize, "sanitize", os.Getenv("SANITIZE") != "false", "Remove scripts, event handlers, unsafe URLs and other HTML the variant's sanitize policy does not allow from stored chapter content (env: SANITIZE)")
+	flags.BoolVar(&cfg.PurgeCSS, "purge-css", os.Getenv("PURGE_CSS") != "false", "Remove stylesheet rules for classes no generated page uses (env: PURGE_CSS)")
+	flags.BoolVar(&cfg.Search, "search", os.Getenv("SEARCH") != "false", "Write a full-text search index of the chapters and a search page that queries it in the browser (env: SEARCH)")
+	flags.StringVar(&cfg.Typography, "typography", envOrDefault("TYPOGRAPHY", "nfc,quotes,dashes,ellipses,spacing"), "Typography fixes for chapter text in each novel's language: nfc, quotes, dashes, ellipses and spacing, or all or none (env: TYPOGRAPHY)")
+	flags.StringVar(&cfg.Safelist, "purge-safelist", os.Getenv("PURGE_SAFELIST"), "Comma-separated classes or prefix-* patterns the CSS purge keeps, e.g. for classes added by scripts (env: PURGE_SAFELIST)")
+	flags.StringVar(&cfg.LogLevel, "log-level", envOrDefault("LOG_LEVEL", "info"), "Log level: debug, info, warn or error (env: LOG_LEVEL)")
+	flags.StringVar(&cfg.LogFormat, "log-format", envOrDefault("LOG_FORMAT", "text"), "Log output format: text or json (env: LOG_FORMAT)")
 
-	// Basic validation
-	if cfg.DBUser == "" || cfg.DBHost == "" || cfg.DBPort == "" || cfg.DBName == "" {
-		return nil, errors.New("database credentials (user, host, port, name) are required")
+	switch command {
+	case "import":
+		registerImportFlags(flags, &cfg.Import)
+	case "serve":
+		flags.StringVar(&cfg.Serve.Addr, "addr", envOrDefault("SERVE_ADDR", "localhost:8080"), "Address the preview server listens on (env: SERVE_ADDR)")
+		flags.DurationVar(&cfg.Serve.Poll, "poll", envDuration("SERVE_POLL", 500*time.Millisecond), "How often the theme and content directories are checked for changes; the built-in theme is watched only when run from the source checkout, where themes/default exists (env: SERVE_POLL)")
+	case "watch":
+		flags.DurationVar(&cfg.Watch.Interval, "interval", envDuration("WATCH_INTERVAL", 10*time.Second), "How often the database is checked for changed novels, volumes and chapters (env: WATCH_INTERVAL)")
 	}
-	if cfg.OutputDir == "" {
-		return nil, errors.New("output directory is required")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", flags.Args())
 	}
-	// DB Password can be empty, but often required. Add check if necessary.
 
 	return cfg, nil
 }
 
+// registerImportFlags defines the flags of the import command.
+func registerImportFlags(flags *flag.FlagSet, c *ImportConfig) {
+	flags.StringVar(&c.File, "file", os.Getenv("IMPORT_FILE"), "Text file of the novel to split into volumes and chapters (env: IMPORT_FILE)")
+	flags.StringVar(&c.Novel, "novel", os.Getenv("IMPORT_NOVEL"), "Name of the novel; an existing novel of that name gets the new volumes and chapters (env: IMPORT_NOVEL)")
+	flags.StringVar(&c.Author, "author", os.Getenv("IMPORT_AUTHOR"), "Author of a new novel (env: IMPORT_AUTHOR)")
+	flags.StringVar(&c.Status, "status", envOrDefault("IMPORT_STATUS", "ongoing"), "Status of a new novel: ongoing, finished or hiatus (env: IMPORT_STATUS)")
+	flags.StringVar(&c.Description, "description", os.Getenv("IMPORT_DESCRIPTION"), "Description of a new novel (env: IMPORT_DESCRIPTION)")
+	flags.StringVar(&c.Lang, "novel-lang", os.Getenv("IMPORT_LANG"), "Language of a new novel, e.g. es; empty uses the site language (env: IMPORT_LANG)")
+	flags.StringVar(&c.VolumePattern, "volume-regex", envOrDefault("IMPORT_VOLUME_REGEX", `^VOLUME \d+`), "Regular expression of the lines starting a volume (env: IMPORT_VOLUME_REGEX)")
+	flags.StringVar(&c.ChapterSplitter, "chapter-splitter", envOrDefault("IMPORT_CHAPTER_SPLITTER", "adventureworkstl.fyi"), "Text of the lines starting a chapter (env: IMPORT_CHAPTER_SPLITTER)")
+	flags.StringVar(&c.ChapterEnding, "chapter-ending", envOrDefault("IMPORT_CHAPTER_ENDING", `^#{4,}`), "Regular expression of the lines ending a chapter (env: IMPORT_CHAPTER_ENDING)")
+	flags.IntVar(&c.StartChapterAt, "start-chapter", envInt("IMPORT_START_CHAPTER", 1), "Number of the first chapter of each volume (env: IMPORT_START_CHAPTER)")
+	flags.IntVar(&c.StartVolumeAt, "start-volume", envInt("IMPORT_START_VOLUME", 1), "Number of the first volume (env: IMPORT_START_VOLUME)")
+	flags.BoolVar(&c.Preview, "preview", os.Getenv("IMPORT_PREVIEW") == "true", "Print the volumes and chapters found without writing to the database (env: IMPORT_PREVIEW)")
+}
+
+// RequireDatabase checks that the database settings needed to connect are present.
+func (c *Config) RequireDatabase() error {
+	if c.DBUser == "" || c.DBHost == "" || c.DBPort == "" || c.DBName == "" {
+		return errors.New("database credentials (user, host, port, name) are required")
+	}
+	// DB Password can be empty, but often required. Add check if necessary.
+	return nil
+}
+
+// RequireOutput checks that an output directory is configured.
+func (c *Config) RequireOutput() error {
+	if c.OutputDir == "" {
+		return errors.New("output directory is required")
+	}
+	return nil
+}
+
+// envOrDefault returns the value of the environment variable key, or def if it is unset or empty.
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt returns the integer value of the environment variable key, or def if
+// it is unset or not a number.
+func envInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return def
+}
+
+// envDuration returns the duration in the environment variable key, such as
+// "2s", or def if it is unset or not a duration.
+func envDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return def
+}
+
 // DSN generates the Data Source Name string for connecting to the database.
 func (c *Config) DSN() string {
 	// username:password@protocol(address)/dbname?param=value
@@ -51,4 +188,4 @@ func (c *Config) DSN() string {
 	// although not strictly required for the current model. It's good practice.
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
 		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
-}
\ No newline at end of file
+}
@@ -0,0 +1,182 @@
+package content
+
+import (
+	"NovelStaticGenerator/internal/markdown"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/utils"
+	"bufio"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLayout is the page template used when the front matter sets none.
+const DefaultLayout = "page"
+
+// frontMatterDelimiter opens and closes the front matter block.
+const frontMatterDelimiter = "---"
+
+// LoadPages reads every .md file under dir into a Page. A missing directory is
+// not an error: the site simply has no content pages. Drafts are skipped.
+func LoadPages(dir string) ([]*models.Page, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		slog.Debug("no content directory, skipping content pages", "dir", dir)
+		return nil, nil
+	}
+	return loadPagesFS(os.DirFS(dir), dir)
+}
+
+// loadPagesFS walks fsys for Markdown files; dir is only used for messages.
+func loadPagesFS(fsys fs.FS, dir string) ([]*models.Page, error) {
+	var pages []*models.Page
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".md" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("could not read content file '%s': %w", p, err)
+		}
+		page, err := ParsePage(p, string(data))
+		if err != nil {
+			return err
+		}
+		if page.Draft {
+			slog.Debug("skipping draft content page", "file", p)
+			return nil
+		}
+		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not load content pages from '%s': %w", dir, err)
+	}
+	slog.Info("loaded content pages", "dir", dir, "pages", len(pages))
+	return pages, nil
+}
+
+// ParsePage parses one Markdown file with optional front matter. source is the
+// file path relative to the content directory; it provides the default slug
+// ("help/faq.md" becomes "help/faq") and title.
+func ParsePage(source, text string) (*models.Page, error) {
+	meta, body, err := splitFrontMatter(text)
+	if err != nil {
+		return nil, fmt.Errorf("content file '%s': %w", source, err)
+	}
+
+	slug := strings.TrimSuffix(source, path.Ext(source))
+	page := &models.Page{
+		Title:  path.Base(slug),
+		Slug:   slug,
+		Layout: DefaultLayout,
+		Params: map[string]string{},
+		Source: source,
+	}
+	for key, value := range meta {
+		switch key {
+		case "title":
+			page.Title = value
+		case "slug":
+			page.Slug = value
+		case "description":
+			page.Description = value
+		case "layout":
+			page.Layout = value
+		case "menu":
+			page.Menu = value == "true" || value == "yes" || value == "main"
+		case "weight":
+			weight, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("content file '%s': weight %q is not a number", source, value)
+			}
+			page.Weight = weight
+		case "lang":
+			page.Lang = value
+		case "draft":
+			page.Draft = value == "true" || value == "yes"
+		default:
+			page.Params[key] = value
+		}
+	}
+
+	// Keep each path segment URL-friendly while preserving the directory structure
+	segments := strings.Split(strings.Trim(page.Slug, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = utils.Slugify(segment)
+	}
+	page.Slug = strings.Join(segments, "/")
+
+	page.Content = template.HTML(markdown.RenderTrusted(body))
+	return page, nil
+}
+
+// splitFrontMatter separates a leading "---" block of "key: value" lines from
+// the Markdown body. Keys are lower-cased; values lose surrounding quotes.
+func splitFrontMatter(text string) (map[string]string, string, error) {
+	meta := map[string]string{}
+	text = strings.ReplaceAll(strings.TrimPrefix(text, "\uFEFF"), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelimiter+"\n") {
+		return meta, text, nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Scan() // opening delimiter
+	consumed := len(scanner.Text()) + 1
+	for scanner.Scan() {
+		line := scanner.Text()
+		consumed += len(line) + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == frontMatterDelimiter {
+			if consumed > len(text) {
+				consumed = len(text)
+			}
+			return meta, text[consumed:], nil
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, "", fmt.Errorf("invalid front matter line %q (want key: value)", trimmed)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		meta[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	return nil, "", errors.New("front matter is not closed with ---")
+}
+
+// Menu returns the pages flagged for the navigation menu, ordered by weight then title.
+func Menu(pages []*models.Page) []*models.Page {
+	var menu []*models.Page
+	for _, page := range pages {
+		if page.Menu {
+			menu = append(menu, page)
+		}
+	}
+	sort.SliceStable(menu, func(i, j int) bool {
+		if menu[i].Weight != menu[j].Weight {
+			return menu[i].Weight < menu[j].Weight
+		}
+		return menu[i].Title < menu[j].Title
+	})
+	return menu
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Summary is the size and last update of the novels, volumes and chapters
+// tables. Any insert or delete changes a count and any update a time, so two
+// equal summaries mean nothing was changed in between, except for a second
+// update within the same second as the last one: updated_at has one-second
+// resolution.
+type Summary struct {
+	Novels, Volumes, Chapters                      int
+	NovelsUpdated, VolumesUpdated, ChaptersUpdated time.Time // Zero for empty tables, and VolumesUpdated without the column
+}
+
+// NovelSummary is the Summary of one novel's rows.
+type NovelSummary struct {
+	Updated                         time.Time
+	Volumes, Chapters               int
+	VolumesUpdated, ChaptersUpdated time.Time
+}
+
+// ChangeTracker reads summaries of the tables the site is built from, for
+// watching them for changes.
+type ChangeTracker struct {
+	db             *sql.DB
+	volumesUpdated string // Expression for the updated_at of volumes, NULL on databases without the column
+}
+
+// NewChangeTracker returns a tracker for db. volumes.updated_at was added after
+// the other tables (see NovelFormatter.sql); without it changes to volumes are
+// only seen when their number changes.
+func NewChangeTracker(db *sql.DB) (*ChangeTracker, error) {
+	hasUpdated, err := hasColumn(db, "volumes", "updated_at")
+	if err != nil {
+		return nil, err
+	}
+	t := &ChangeTracker{db: db, volumesUpdated: "NULL"}
+	if hasUpdated {
+		t.volumesUpdated = "MAX(updated_at)"
+	} else {
+		slog.Warn("volumes has no updated_at column, edits to volume titles are not noticed until a novel or chapter changes")
+	}
+	return t, nil
+}
+
+// Summary reads the summary of the whole database.
+func (t *ChangeTracker) Summary() (Summary, error) {
+	var s Summary
+	var novelsUpdated, volumesUpdated, chaptersUpdated sql.NullTime
+	err := t.db.QueryRow(`
+        SELECT (SELECT COUNT(*) FROM novels), (SELECT MAX(updated_at) FROM novels),
+               (SELECT COUNT(*) FROM volumes), (SELECT `+t.volumesUpdated+` FROM volumes),
+               (SELECT COUNT(*) FROM chapters), (SELECT MAX(updated_at) FROM chapters)
+    `).Scan(&s.Novels, &novelsUpdated, &s.Volumes, &volumesUpdated, &s.Chapters, &chaptersUpdated)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to read the table summary: %w", err)
+	}
+	s.NovelsUpdated, s.VolumesUpdated, s.ChaptersUpdated = utcTime(novelsUpdated), utcTime(volumesUpdated), utcTime(chaptersUpdated)
+	return s, nil
+}
+
+// Novels reads the summary of every novel by novel ID.
+func (t *ChangeTracker) Novels() (map[int]NovelSummary, error) {
+	query := `
+        SELECT n.novel_id, n.updated_at,
+               COALESCE(v.n_rows, 0), v.updated, COALESCE(c.n_rows, 0), c.updated
+        FROM novels n
+        LEFT JOIN (SELECT novel_id, COUNT(*) AS n_rows, ` + t.volumesUpdated + ` AS updated FROM volumes GROUP BY novel_id) v ON v.novel_id = n.novel_id
+        LEFT JOIN (SELECT novel_id, COUNT(*) AS n_rows, MAX(updated_at) AS updated FROM chapters GROUP BY novel_id) c ON c.novel_id = n.novel_id
+    `
+	rows, err := t.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute novel summary query: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make(map[int]NovelSummary)
+	for rows.Next() {
+		var id int
+		var s NovelSummary
+		var updated, volumesUpdated, chaptersUpdated sql.NullTime
+		if err := rows.Scan(&id, &updated, &s.Volumes, &volumesUpdated, &s.Chapters, &chaptersUpdated); err != nil {
+			return nil, fmt.Errorf("failed to scan novel summary row: %w", err)
+		}
+		s.Updated, s.VolumesUpdated, s.ChaptersUpdated = utcTime(updated), utcTime(volumesUpdated), utcTime(chaptersUpdated)
+		summaries[id] = s
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error encountered during novel summary row iteration: %w", err)
+	}
+	return summaries, nil
+}
+
+// utcTime returns t in UTC, or the zero time for NULL, so that summaries can
+// be compared with ==.
+func utcTime(t sql.NullTime) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Time.UTC()
+}
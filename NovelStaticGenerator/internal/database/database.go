@@ -1,12 +1,17 @@
 package database
 
 import (
+	"NovelStaticGenerator/internal/models" // Adjust import path if needed
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"NovelStaticGenerator/internal/models" // Adjust import path if needed
+	"html/template"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql" // MySQL driver
+	"github.com/go-sql-driver/mysql" // MySQL driver
 )
 
 // ConnectDB establishes a connection to the database using the provided DSN.
@@ -23,22 +28,59 @@ func ConnectDB(dsn string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Database connection established successfully.")
+	slog.Info("database connection established")
 	return db, nil
 }
 
+// ContentColumns lists the chapter columns holding pre-rendered HTML that
+// variants may render.
+var ContentColumns = []string{"content_html", "content_bulma"}
+
+// PlainColumn holds the unformatted chapter text variants with a format render.
+const PlainColumn = "content_plain"
+
 // FetchAllChapters retrieves all chapters from the database, ordered by novel name and chapter number.
-func FetchAllChapters(db *sql.DB) ([]*models.Chapter, error) {
-	// Adjust the query if your column names are different or if you add a title column
+// Only the content columns listed in columns are loaded (see ContentColumns and PlainColumn).
+func FetchAllChapters(db *sql.DB, columns []string) ([]*models.Chapter, error) {
+	return fetchChapters(db, columns, "")
+}
+
+// FetchNovelChapters retrieves the chapters of the given novels, in the order
+// of FetchAllChapters.
+func FetchNovelChapters(db *sql.DB, columns []string, novelIDs []int) ([]*models.Chapter, error) {
+	if len(novelIDs) == 0 {
+		return []*models.Chapter{}, nil
+	}
+	args := make([]any, len(novelIDs))
+	for i, id := range novelIDs {
+		args[i] = id
+	}
+	return fetchChapters(db, columns, "WHERE c.novel_id IN (?"+strings.Repeat(", ?", len(novelIDs)-1)+")", args...)
+}
+
+// fetchChapters runs the chapter query with an optional WHERE clause.
+func fetchChapters(db *sql.DB, columns []string, where string, args ...any) ([]*models.Chapter, error) {
+	start := time.Now()
+	var selectContent strings.Builder
+	for _, column := range columns {
+		if column != PlainColumn && !slices.Contains(ContentColumns, column) {
+			return nil, fmt.Errorf("unknown chapter content column %q", column)
+		}
+		selectContent.WriteString(", c." + column) // Safe: checked against the known columns
+	}
+
+	// Adjust the query if your column names are different
 	query := `
-        SELECT c.chapter_id, n.name AS novel_name, v.volume_number, c.chapter_number, c.content_html, c.content_bulma
+        SELECT c.chapter_id, c.novel_id, c.volume_id, n.name AS novel_name, v.volume_number, c.chapter_number,
+               COALESCE(c.title, ''), c.created_at, c.updated_at` + selectContent.String() + `
         FROM chapters c
         INNER JOIN novels n ON n.novel_id = c.novel_id  -- Ensure correct join column names
         INNER JOIN volumes v ON v.volume_id = c.volume_id -- Ensure correct join column names
+        ` + where + `
         ORDER BY novel_name, v.volume_number, c.chapter_number
     `
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute chapter query: %w", err)
 	}
@@ -48,21 +90,39 @@ func FetchAllChapters(db *sql.DB) ([]*models.Chapter, error) {
 
 	for rows.Next() {
 		chapter := &models.Chapter{} // Create a new Chapter struct for each row
-		// Add &chapter.Title if you have a title column
-		err := rows.Scan(
+		var createdAt, updatedAt sql.NullTime
+		content := make([]sql.NullString, len(columns))
+		dest := []any{
 			&chapter.ID,
+			&chapter.NovelID,
+			&chapter.VolumeID,
 			&chapter.NovelName,
 			&chapter.VolumeNumber,
 			&chapter.ChapterNumber,
-			&chapter.ContentHTML,  // Scan directly into template.HTML
-			&chapter.ContentBulma, // Scan directly into template.HTML
-		)
+			&chapter.Title,
+			&createdAt,
+			&updatedAt,
+		}
+		for i := range content {
+			dest = append(dest, &content[i])
+		}
+		err := rows.Scan(dest...)
 		if err != nil {
 			// Consider logging the error and skipping the row vs failing entirely
-			log.Printf("Warning: Failed to scan chapter row: %v", err)
+			slog.Warn("failed to scan chapter row, skipping", "error", err)
 			continue // Skip this row and proceed with others
 			// OR: return nil, fmt.Errorf("failed to scan chapter row: %w", err) // Fail hard
 		}
+		chapter.CreatedAt = createdAt.Time
+		chapter.UpdatedAt = updatedAt.Time
+		chapter.Content = make(map[string]template.HTML, len(columns))
+		for i, column := range columns {
+			if column == PlainColumn {
+				chapter.Plain = content[i].String
+				continue
+			}
+			chapter.Content[column] = template.HTML(content[i].String) // Trusted HTML written by the publisher
+		}
 		chapters = append(chapters, chapter)
 	}
 
@@ -72,10 +132,163 @@ func FetchAllChapters(db *sql.DB) ([]*models.Chapter, error) {
 	}
 
 	if len(chapters) == 0 {
-		log.Println("Warning: No chapters found in the database.")
+		slog.Warn("no chapters found in the database")
 	} else {
-		log.Printf("Fetched %d chapters from the database.", len(chapters))
+		slog.Info("fetched chapters", "count", len(chapters), "duration", time.Since(start))
 	}
 
 	return chapters, nil
-}
\ No newline at end of file
+}
+
+// FetchAllNovels retrieves every novel with its volumes, ordered by novel name and volume number.
+// Chapters are not attached here; the generator links them up from FetchAllChapters.
+// On a database without the lang column (see NovelFormatter.sql) every novel is
+// in the site language.
+func FetchAllNovels(db *sql.DB) ([]*models.Novel, error) {
+	lang := "COALESCE(lang, '')"
+	hasLang, err := hasColumn(db, "novels", "lang")
+	if err != nil {
+		return nil, err
+	}
+	if !hasLang {
+		lang = "''"
+		slog.Warn("novels has no lang column, every novel uses the site language; add it with the migration in NovelFormatter.sql")
+	}
+	query := `
+        SELECT novel_id, name, author, status, COALESCE(description, ''), ` + lang + `
+        FROM novels
+        ORDER BY name
+    `
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute novel query: %w", err)
+	}
+	defer rows.Close()
+
+	novels := []*models.Novel{}
+	byID := make(map[int]*models.Novel)
+	for rows.Next() {
+		novel := &models.Novel{}
+		if err := rows.Scan(&novel.ID, &novel.Name, &novel.Author, &novel.Status, &novel.Description, &novel.Lang); err != nil {
+			slog.Warn("failed to scan novel row, skipping", "error", err)
+			continue
+		}
+		novels = append(novels, novel)
+		byID[novel.ID] = novel
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error encountered during novel row iteration: %w", err)
+	}
+
+	volumes, err := fetchAllVolumes(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range volumes {
+		novel, ok := byID[volume.NovelID]
+		if !ok {
+			slog.Warn("volume references unknown novel, skipping", "volume_id", volume.ID, "novel_id", volume.NovelID)
+			continue
+		}
+		novel.Volumes = append(novel.Volumes, volume)
+	}
+
+	slog.Info("fetched novels", "count", len(novels), "volumes", len(volumes))
+	return novels, nil
+}
+
+// fetchAllVolumes retrieves all volumes ordered by novel and volume number.
+func fetchAllVolumes(db *sql.DB) ([]*models.Volume, error) {
+	query := `
+        SELECT volume_id, novel_id, COALESCE(volume_number, 0), COALESCE(title, ''), COALESCE(description, '')
+        FROM volumes
+        ORDER BY novel_id, volume_number
+    `
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute volume query: %w", err)
+	}
+	defer rows.Close()
+
+	volumes := []*models.Volume{}
+	for rows.Next() {
+		volume := &models.Volume{}
+		if err := rows.Scan(&volume.ID, &volume.NovelID, &volume.Number, &volume.Title, &volume.Description); err != nil {
+			slog.Warn("failed to scan volume row, skipping", "error", err)
+			continue
+		}
+		volumes = append(volumes, volume)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error encountered during volume row iteration: %w", err)
+	}
+	return volumes, nil
+}
+
+// queryer is a *sql.DB or a *sql.Tx.
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// hasColumn reports whether table has column, for the columns added to the
+// schema after the first release: databases created before them keep working
+// until they are migrated.
+func hasColumn(db queryer, table, column string) (bool, error) {
+	var n int
+	err := db.QueryRow(`
+        SELECT COUNT(*) FROM information_schema.columns
+        WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+    `, table, column).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up the columns of %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+// errNoSuchTable is MySQL's ER_NO_SUCH_TABLE error number.
+const errNoSuchTable = 1146
+
+// FetchAllCharacters retrieves the character glossary ordered by name. A
+// database without the characters table has no glossary rather than failing.
+func FetchAllCharacters(db *sql.DB) ([]*models.Character, error) {
+	query := `
+        SELECT character_id, name, COALESCE(aliases, ''), COALESCE(description, '')
+        FROM characters
+        ORDER BY name
+    `
+
+	rows, err := db.Query(query)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable {
+			slog.Info("no characters table, skipping the character glossary")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to execute character query: %w", err)
+	}
+	defer rows.Close()
+
+	characters := []*models.Character{}
+	for rows.Next() {
+		character := &models.Character{}
+		var aliases string
+		if err := rows.Scan(&character.ID, &character.Name, &aliases, &character.Description); err != nil {
+			slog.Warn("failed to scan character row, skipping", "error", err)
+			continue
+		}
+		for _, alias := range strings.Split(aliases, ",") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				character.Aliases = append(character.Aliases, alias)
+			}
+		}
+		characters = append(characters, character)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error encountered during character row iteration: %w", err)
+	}
+
+	slog.Info("fetched characters", "count", len(characters))
+	return characters, nil
+}
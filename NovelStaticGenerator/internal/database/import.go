@@ -0,0 +1,160 @@
+package database
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ImportNovel writes novel, its volumes and chapters in one transaction,
+// setting their IDs. A novel already in the database under the same name is
+// added to rather than duplicated, and so are its volumes with the same number;
+// the other novel and volume fields are then left as they are. Nothing is
+// written if any of the chapters already exists.
+//
+// Chapters are matched to volumes by VolumeNumber. Their Plain text goes into
+// content_plain and their Content into the columns it is keyed by, which must
+// be among ContentColumns.
+func ImportNovel(db *sql.DB, novel *models.Novel, chapters []*models.Chapter) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start import transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback() // The error that made the import fail is the one worth reporting
+		}
+	}()
+
+	if err := importNovelRow(tx, novel); err != nil {
+		return err
+	}
+	volumeIDs := make(map[int]int, len(novel.Volumes))
+	for _, volume := range novel.Volumes {
+		volume.NovelID = novel.ID
+		if err := importVolumeRow(tx, volume); err != nil {
+			return err
+		}
+		volumeIDs[volume.Number] = volume.ID
+	}
+	for _, chapter := range chapters {
+		volumeID, ok := volumeIDs[chapter.VolumeNumber]
+		if !ok {
+			return fmt.Errorf("chapter %d is in volume %d, which is not being imported", chapter.ChapterNumber, chapter.VolumeNumber)
+		}
+		chapter.NovelID, chapter.VolumeID, chapter.NovelName = novel.ID, volumeID, novel.Name
+		if err := importChapterRow(tx, chapter); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	slog.Info("imported novel", "novel", novel.Name, "novel_id", novel.ID, "volumes", len(novel.Volumes), "chapters", len(chapters))
+	return nil
+}
+
+// importNovelRow looks novel up by name and inserts it if it is not there.
+func importNovelRow(tx *sql.Tx, novel *models.Novel) error {
+	err := tx.QueryRow(`SELECT novel_id FROM novels WHERE name = ?`, novel.Name).Scan(&novel.ID)
+	if err == nil {
+		slog.Info("adding to existing novel", "novel", novel.Name, "novel_id", novel.ID)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to look up novel '%s': %w", novel.Name, err)
+	}
+	if novel.Author == "" {
+		return fmt.Errorf("novel '%s' is new and needs an author", novel.Name)
+	}
+	hasLang, err := hasColumn(tx, "novels", "lang")
+	if err != nil {
+		return err
+	}
+	if !hasLang && novel.Lang != "" {
+		return fmt.Errorf("novels has no lang column to store '%s' in; add it with the migration in NovelFormatter.sql or import without a language", novel.Lang)
+	}
+
+	columns, placeholders := "name, author, status, description", "?, ?, ?, ?"
+	args := []any{novel.Name, novel.Author, novel.Status, nullString(novel.Description)}
+	if hasLang {
+		columns, placeholders = columns+", lang", placeholders+", ?"
+		args = append(args, nullString(novel.Lang))
+	}
+	result, err := tx.Exec(`INSERT INTO novels (`+columns+`) VALUES (`+placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert novel '%s': %w", novel.Name, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read the id of novel '%s': %w", novel.Name, err)
+	}
+	novel.ID = int(id)
+	return nil
+}
+
+// importVolumeRow looks volume up by novel and number and inserts it if it is
+// not there.
+func importVolumeRow(tx *sql.Tx, volume *models.Volume) error {
+	err := tx.QueryRow(`SELECT volume_id FROM volumes WHERE novel_id = ? AND volume_number = ?`, volume.NovelID, volume.Number).Scan(&volume.ID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to look up volume %d: %w", volume.Number, err)
+	}
+
+	result, err := tx.Exec(`INSERT INTO volumes (novel_id, title, volume_number, description) VALUES (?, ?, ?, ?)`,
+		volume.NovelID, volume.Title, volume.Number, nullString(volume.Description))
+	if err != nil {
+		return fmt.Errorf("failed to insert volume %d: %w", volume.Number, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read the id of volume %d: %w", volume.Number, err)
+	}
+	volume.ID = int(id)
+	return nil
+}
+
+// importChapterRow inserts chapter, failing if its volume already has a
+// chapter with the same number.
+func importChapterRow(tx *sql.Tx, chapter *models.Chapter) error {
+	var exists int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM chapters WHERE volume_id = ? AND chapter_number = ?`, chapter.VolumeID, chapter.ChapterNumber).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to look up chapter %d of volume %d: %w", chapter.ChapterNumber, chapter.VolumeNumber, err)
+	}
+	if exists > 0 {
+		return fmt.Errorf("volume %d already has a chapter %d", chapter.VolumeNumber, chapter.ChapterNumber)
+	}
+
+	columns := "novel_id, volume_id, chapter_number, title, " + PlainColumn
+	placeholders := "?, ?, ?, ?, ?"
+	args := []any{chapter.NovelID, chapter.VolumeID, chapter.ChapterNumber, nullString(chapter.Title), chapter.Plain}
+	for _, column := range ContentColumns {
+		if content, ok := chapter.Content[column]; ok {
+			columns += ", " + column // Safe: one of the known columns
+			placeholders += ", ?"
+			args = append(args, string(content))
+		}
+	}
+	result, err := tx.Exec(`INSERT INTO chapters (`+columns+`) VALUES (`+placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert chapter %d of volume %d: %w", chapter.ChapterNumber, chapter.VolumeNumber, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read the id of chapter %d of volume %d: %w", chapter.ChapterNumber, chapter.VolumeNumber, err)
+	}
+	chapter.ID = int(id)
+	return nil
+}
+
+// nullString stores "" as NULL, which the fetch queries read back as "".
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,135 @@
+// Package devserver serves a site built into memory, for previewing theme and
+// content changes, and makes the open pages reload when a new build of the
+// site is published. Each HTML page served gets a small script that listens
+// for the reload on ReloadPath; the built pages themselves are not changed.
+package devserver
+
+import (
+	"NovelStaticGenerator/internal/generator"
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ReloadPath is the event stream the pages listen on for reloads.
+const ReloadPath = "/__livereload"
+
+// reloadScript is added to every HTML page served.
+const reloadScript = `<script>new EventSource("` + ReloadPath + `").onmessage = function () { location.reload(); };</script>`
+
+// Server serves the latest published build.
+type Server struct {
+	mu      sync.RWMutex
+	site    *generator.MemoryOutput
+	changed chan struct{} // Closed when the next build is published
+}
+
+// New returns a Server with nothing to serve yet.
+func New() *Server {
+	return &Server{site: generator.NewMemoryOutput(), changed: make(chan struct{})}
+}
+
+// Publish serves site from now on and tells the open pages to reload. site
+// must not be written to afterwards.
+func (s *Server) Publish(site *generator.MemoryOutput) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.site = site
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// current returns the site being served and the channel closed when it is
+// replaced.
+func (s *Server) current() (*generator.MemoryOutput, chan struct{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.site, s.changed
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ReloadPath {
+		s.serveReload(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	site, _ := s.current()
+	relPath := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if relPath == "" || strings.HasSuffix(r.URL.Path, "/") {
+		relPath = path.Join(relPath, "index.html")
+	} else if _, ok := site.File(relPath); !ok {
+		// A directory: redirect to it with a slash, or its relative links break
+		if _, ok := site.File(relPath + "/index.html"); ok {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+	}
+	data, ok := site.File(relPath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(relPath))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	if strings.HasPrefix(contentType, "text/html") {
+		data = injectReload(data)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store") // Fingerprints change with every edit anyway
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
+
+// serveReload streams an event to the page whenever a new build is published,
+// until the page goes away.
+func (s *Server) serveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, "retry: 1000\n\n") // Reconnect quickly when the server restarts
+	flusher.Flush()
+
+	_, changed := s.current()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+			_, changed = s.current()
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// injectReload adds the reload script to a page, before its </body> if it has
+// one.
+func injectReload(page []byte) []byte {
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		i = len(page)
+	}
+	out := make([]byte, 0, len(page)+len(reloadScript))
+	out = append(out, page[:i]...)
+	out = append(out, reloadScript...)
+	return append(out, page[i:]...)
+}
@@ -0,0 +1,61 @@
+package devserver
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Watch calls onChange whenever a file under dirs is added, removed or
+// modified, until ctx is done. It polls every interval rather than relying on
+// file system notifications, which editors that save by renaming and network
+// drives do not always send. Missing directories are watched for appearing.
+// onChange runs on Watch's goroutine, so changes made while it runs are seen
+// on the next check.
+func Watch(ctx context.Context, interval time.Duration, dirs []string, onChange func()) {
+	last := snapshot(dirs)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if current := snapshot(dirs); current != last {
+			last = current
+			onChange()
+		}
+	}
+}
+
+// snapshot hashes the path, size and modification time of every file under
+// dirs.
+func snapshot(dirs []string) uint64 {
+	h := fnv.New64a()
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil // Removed while walking; the next check sees it gone
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00%d\x00", p, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			slog.Debug("could not scan watched directory", "dir", dir, "error", err)
+		}
+	}
+	return h.Sum64()
+}
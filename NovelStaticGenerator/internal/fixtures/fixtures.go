@@ -0,0 +1,111 @@
+package fixtures
+
+import (
+	"NovelStaticGenerator/internal/content"
+	"NovelStaticGenerator/internal/models"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// The fixtures stand in for database rows and content files when templates are
+// checked without a database. They deliberately cover the awkward cases a theme
+// has to cope with: first and last chapters (nil Prev/Next), a novel that is
+// its own only chapter, novels and volumes without chapters, missing titles,
+// authors and descriptions, zero dates and very long names.
+
+// longName is far longer than any sane title, to catch layouts that assume short text.
+var longName = strings.TrimSpace(strings.Repeat("An Extraordinarily Long Novel Title That Keeps Going ", 6))
+
+// Novels returns sample novels and their chapters, linked by ID the way
+// database.FetchAllNovels and database.FetchAllChapters return them.
+func Novels() ([]*models.Novel, []*models.Chapter) {
+	created := time.Date(2024, time.March, 5, 18, 30, 0, 0, time.UTC)
+
+	complete := &models.Novel{
+		ID:          1,
+		Name:        "The Fixture Chronicles",
+		Author:      "A. N. Author",
+		Status:      "ongoing",
+		Lang:        "es",
+		Description: "A *sample* novel with two volumes.\n\nIt has [links](https://example.com) and `code`.",
+		Volumes: []*models.Volume{
+			{ID: 1, NovelID: 1, Number: 1},
+			{ID: 2, NovelID: 1, Number: 2, Title: "The Second Arc", Description: "A volume with a **title** and a description."},
+		},
+	}
+	empty := &models.Novel{
+		ID:      2,
+		Name:    "Empty Novel",
+		Volumes: []*models.Volume{{ID: 3, NovelID: 2, Number: 1}},
+	}
+	long := &models.Novel{
+		ID:          3,
+		Name:        longName,
+		Author:      longName,
+		Status:      "finished",
+		Description: strings.Repeat("A very long description. ", 80),
+	}
+	untitled := &models.Novel{ID: 4, Name: ""}
+
+	chapters := []*models.Chapter{
+		chapter(complete, 1, 1, 1, "The Beginning", created),
+		chapter(complete, 2, 1, 2, "", created.Add(24*time.Hour)),
+		chapter(complete, 3, 1, 3, "A Title With <Markup> & Ampersands", created.Add(48*time.Hour)),
+		chapter(complete, 4, 2, 1, "", time.Time{}),
+		chapter(long, 5, 4, 1, longName, created),
+		chapter(untitled, 6, 5, 1, "", time.Time{}),
+	}
+	chapters[3].Content = map[string]template.HTML{}
+	chapters[3].Plain = ""
+
+	return []*models.Novel{complete, empty, long, untitled}, chapters
+}
+
+// chapter builds a chapter row of novel in the volume with the given ID.
+func chapter(novel *models.Novel, id, volumeID, number int, title string, created time.Time) *models.Chapter {
+	volumeNumber := 1
+	for _, volume := range novel.Volumes {
+		if volume.ID == volumeID {
+			volumeNumber = volume.Number
+		}
+	}
+	return &models.Chapter{
+		ID:            id,
+		NovelID:       novel.ID,
+		VolumeID:      volumeID,
+		NovelName:     novel.Name,
+		ChapterNumber: number,
+		VolumeNumber:  volumeNumber,
+		Title:         title,
+		Content: map[string]template.HTML{
+			"content_html":  template.HTML(fmt.Sprintf("<p>Chapter %d text, where <strong>Saga</strong> meets <strong>Silk</strong>[TN: A <em>silk</em> road name.].</p>\n<p>\"Straight quotes,\" she said -- and <em>dots</em>...</p>\n<p>* * *</p>\n<h3>An Interlude</h3>\n<p>Second paragraph.[^1]</p>\n<p>[^1]: A footnote.</p>", number)),
+			"content_bulma": template.HTML(fmt.Sprintf(`<section class="section"><div class="container"><p class="content">Chapter %d text, where <span class="tag is-info">Saga</span> meets <span class="tag is-info">Silk</span>[TN: A <em>silk</em> road name.].</p></div></section>`, number)),
+		},
+		Plain:     fmt.Sprintf("Chapter %d\n\nChapter %d text, where Saga Ferdio meets Silk[TN: A silk road name.].\nA second line with <brackets> & ampersands.\n\n  Silk (Hello there.)\n\n* * *\n\nSecond paragraph.[^1]\n[^1]: A footnote (with parentheses).", number, number),
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+}
+
+// Characters returns a sample glossary: a character with aliases and a
+// description, one named in no chapter and one with only a name.
+func Characters() []*models.Character {
+	return []*models.Character{
+		{ID: 1, Name: "Saga Ferdio", Aliases: []string{"Saga", "Tío Saga"}, Description: "The *protagonist*. Appears in [every chapter](https://example.com)."},
+		{ID: 2, Name: "Nobody", Description: strings.Repeat("Never named in any chapter. ", 20)},
+		{ID: 3, Name: "Silk"},
+	}
+}
+
+// Pages returns sample content pages: a full one in the menu and a bare one
+// with no title, description, parameters or body.
+func Pages() []*models.Page {
+	about, err := content.ParsePage("about.md", "---\ntitle: About\ndescription: About this site\nmenu: true\nweight: 1\nsubtitle: Fixture parameter\n---\n# About\n\nSome *Markdown* text.\n")
+	if err != nil {
+		panic(err) // The fixture source is fixed
+	}
+	bare := &models.Page{Slug: "help/bare", Layout: content.DefaultLayout, Params: map[string]string{}, Source: "help/bare.md"}
+	return []*models.Page{about, bare}
+}
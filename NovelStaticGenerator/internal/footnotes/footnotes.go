@@ -0,0 +1,341 @@
+// Package footnotes turns footnote markers in chapter HTML into numbered
+// references and a list of notes at the end of the chapter.
+//
+// Two kinds of markers are understood in the text:
+//
+//	Saga drew the katana[^blade].     a reference to a note defined elsewhere
+//	[^blade]: A single-edged sword.   its definition, a paragraph of its own
+//	Mamaru-san[TN: An honorific.]     a translator's note, written in place
+//
+// Translator notes may also be written [T/N: …], [N/T: …] or [N. del T.: …].
+// Markers inside <code> and <pre> are left alone.
+package footnotes
+
+import (
+	"NovelStaticGenerator/internal/htmltoken"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	// markerRegex finds a reference (group 1 is its label) or the start of a
+	// translator's note.
+	markerRegex = regexp.MustCompile(`\[\^([^\]\s]+)\]|\[(?i:TN|T/N|N/T|N\. ?del ?T\.)\s*:\s*`)
+
+	// definitionRegex matches the start of a paragraph defining a note.
+	definitionRegex = regexp.MustCompile(`^\s*\[\^([^\]\s]+)\]:\s*`)
+)
+
+// blockElements end a translator's note that was never closed with ']'.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "blockquote": true, "li": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "table": true, "pre": true,
+}
+
+// Options selects how notes are shown.
+type Options struct {
+	Popovers bool   // Open each note in a popover from its reference, besides listing it at the end
+	Class    string // Class of the notes section, e.g. "section content is-small"
+}
+
+type note struct {
+	number int
+	html   string
+	refs   int // References made so far
+}
+
+// Render replaces the markers in content with superscript references linking
+// to a numbered list of the notes, appended to the content, whose entries link
+// back to each reference. It also returns the problems found: references to
+// notes that are not defined, definitions nobody references and translator
+// notes without their closing ']'. Such markers are left as they are.
+func Render(content template.HTML, opts Options) (template.HTML, []string) {
+	if !strings.Contains(string(content), "[") {
+		return content, nil
+	}
+	r := &renderer{opts: opts, defined: make(map[string]string), byLabel: make(map[string]*note)}
+	tokens := r.extractDefinitions(string(content))
+	body := r.render(tokens)
+	for label := range r.defined {
+		if r.byLabel[label] == nil {
+			r.problems = append(r.problems, fmt.Sprintf("note [^%s] is defined but never referenced", label))
+		}
+	}
+	if len(r.notes) == 0 {
+		return template.HTML(body), r.problems
+	}
+	return template.HTML(body + r.section()), r.problems
+}
+
+type renderer struct {
+	opts     Options
+	defined  map[string]string // Definition HTML by label
+	byLabel  map[string]*note  // Referenced notes by label
+	notes    []*note           // In order of first reference
+	problems []string
+
+	out        strings.Builder
+	pending    *strings.Builder // Translator's note being read, nil outside one
+	pendingRaw strings.Builder  // The same as it was written, to restore it if the note is never closed
+	pendingAt  string           // Its marker
+	opened     []string         // Tags opened in the note and not yet closed
+	closed     []string         // End tags in the note of elements opened before it
+	drop       []string         // End tags of elements a finished note opened, left out of the text after it
+	code       int              // Depth inside <code> and <pre>
+}
+
+// extractDefinitions tokenizes src, taking out the paragraphs that define notes.
+func (r *renderer) extractDefinitions(src string) []htmltoken.Token {
+	var tokens []htmltoken.Token
+	z := htmltoken.New(src)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, tok)
+		n := len(tokens)
+		if n < 2 || tok.Type != htmltoken.Text || tokens[n-2].Type != htmltoken.StartTag || tokens[n-2].Data != "p" {
+			continue
+		}
+		m := definitionRegex.FindStringSubmatch(tok.Data)
+		if m == nil {
+			continue
+		}
+		// Read the rest of the paragraph as the note
+		var def strings.Builder
+		def.WriteString(tok.Data[len(m[0]):])
+		for {
+			next, ok := z.Next()
+			if !ok || (next.Type == htmltoken.EndTag && next.Data == "p") {
+				break
+			}
+			def.WriteString(next.Raw)
+		}
+		if _, dup := r.defined[m[1]]; dup {
+			r.problems = append(r.problems, fmt.Sprintf("note [^%s] is defined twice, keeping the first", m[1]))
+		} else {
+			r.defined[m[1]] = strings.TrimSpace(def.String())
+		}
+		tokens = tokens[:n-2]
+	}
+}
+
+// render writes the tokens with the markers replaced.
+func (r *renderer) render(tokens []htmltoken.Token) string {
+	for _, tok := range tokens {
+		if r.pending != nil {
+			switch {
+			case tok.Type == htmltoken.Text:
+				if end := strings.IndexByte(tok.Data, ']'); end >= 0 {
+					r.pending.WriteString(tok.Data[:end])
+					r.pendingRaw.WriteString(tok.Data[:end])
+					r.finishNote()
+					r.text(tok.Data[end+1:])
+					continue
+				}
+				r.pending.WriteString(tok.Raw)
+				r.pendingRaw.WriteString(tok.Raw)
+				continue
+			case (tok.Type == htmltoken.StartTag || tok.Type == htmltoken.EndTag) && blockElements[tok.Data]:
+				r.abandonNote()
+			default:
+				r.noteTag(tok)
+				r.pendingRaw.WriteString(tok.Raw)
+				continue
+			}
+		}
+
+		switch tok.Type {
+		case htmltoken.Text:
+			if r.code > 0 {
+				r.out.WriteString(tok.Raw)
+			} else {
+				r.text(tok.Data)
+			}
+			continue
+		case htmltoken.StartTag:
+			if (tok.Data == "code" || tok.Data == "pre") && !tok.SelfClosing {
+				r.code++
+			}
+		case htmltoken.EndTag:
+			if (tok.Data == "code" || tok.Data == "pre") && r.code > 0 {
+				r.code--
+			}
+			if i := lastIndex(r.drop, tok.Data); i >= 0 {
+				r.drop = append(r.drop[:i], r.drop[i+1:]...)
+				continue
+			}
+		}
+		if tok.Type != htmltoken.Comment && blockElements[tok.Data] {
+			r.drop = nil // The elements a note left open end with the block, if not before
+		}
+		r.out.WriteString(tok.Raw)
+	}
+	if r.pending != nil {
+		r.abandonNote()
+	}
+	return r.out.String()
+}
+
+// text writes a text node, replacing its markers.
+func (r *renderer) text(s string) {
+	for {
+		loc := markerRegex.FindStringSubmatchIndex(s)
+		if loc == nil {
+			r.out.WriteString(s)
+			return
+		}
+		r.out.WriteString(s[:loc[0]])
+		marker := s[loc[0]:loc[1]]
+		s = s[loc[1]:]
+
+		if loc[2] >= 0 {
+			label := marker[2 : len(marker)-1]
+			def, ok := r.defined[label]
+			if !ok {
+				r.problems = append(r.problems, fmt.Sprintf("note [^%s] is referenced but not defined", label))
+				r.out.WriteString(marker)
+				continue
+			}
+			n := r.byLabel[label]
+			if n == nil {
+				n = r.newNote(def)
+				r.byLabel[label] = n
+			}
+			r.reference(n)
+			continue
+		}
+
+		// A translator's note, up to the next ']'
+		if end := strings.IndexByte(s, ']'); end >= 0 {
+			r.reference(r.newNote(strings.TrimSpace(s[:end])))
+			s = s[end+1:]
+			continue
+		}
+		r.pending = &strings.Builder{}
+		r.pending.WriteString(s)
+		r.pendingRaw.WriteString(s)
+		r.pendingAt = marker
+		return
+	}
+}
+
+// noteTag writes a tag inside the translator's note being read, keeping the
+// note well-formed: the end tags of elements opened before the note are kept
+// for after its reference, as in "<em>A [TN: b</em> c]" written as
+// "<em>A<sup>…</sup></em>".
+func (r *renderer) noteTag(tok htmltoken.Token) {
+	switch {
+	case tok.Type == htmltoken.StartTag && !tok.SelfClosing && !htmltoken.IsVoid(tok.Data):
+		r.opened = append(r.opened, tok.Data)
+	case tok.Type == htmltoken.EndTag:
+		i := lastIndex(r.opened, tok.Data)
+		if i < 0 {
+			r.closed = append(r.closed, tok.Raw)
+			return
+		}
+		// Elements opened after it and left open end with it
+		for j := len(r.opened) - 1; j > i; j-- {
+			r.pending.WriteString("</" + r.opened[j] + ">")
+		}
+		r.opened = r.opened[:i]
+	}
+	r.pending.WriteString(tok.Raw)
+}
+
+// finishNote ends the translator's note being read. The elements opened in it
+// are closed at its end and their end tags after it left out, so that
+// "[TN: a <em>b] c</em>" becomes a note "a <em>b</em>" followed by " c".
+func (r *renderer) finishNote() {
+	for i := len(r.opened) - 1; i >= 0; i-- {
+		r.pending.WriteString("</" + r.opened[i] + ">")
+	}
+	text := strings.TrimSpace(r.pending.String())
+	r.drop = append(r.drop, r.opened...)
+	closed := r.closed
+	r.resetNote()
+	r.reference(r.newNote(text))
+	for _, end := range closed {
+		r.out.WriteString(end)
+	}
+}
+
+// abandonNote writes back a translator's note that was never closed.
+func (r *renderer) abandonNote() {
+	r.problems = append(r.problems, fmt.Sprintf("translator's note %q is not closed with ']'", strings.TrimSpace(r.pendingAt)))
+	r.out.WriteString(r.pendingAt)
+	r.out.WriteString(r.pendingRaw.String())
+	r.resetNote()
+}
+
+// resetNote forgets the translator's note being read.
+func (r *renderer) resetNote() {
+	r.pending = nil
+	r.pendingRaw.Reset()
+	r.opened, r.closed = nil, nil
+}
+
+func (r *renderer) newNote(content string) *note {
+	n := &note{number: len(r.notes) + 1, html: content}
+	r.notes = append(r.notes, n)
+	return n
+}
+
+// reference writes a reference to n.
+func (r *renderer) reference(n *note) {
+	n.refs++
+	id := refID(n.number, n.refs)
+	if !r.opts.Popovers {
+		fmt.Fprintf(&r.out, `<sup class="footnote-ref" id="%s"><a href="#fn-%d" role="doc-noteref">%d</a></sup>`, id, n.number, n.number)
+		return
+	}
+	popover := fmt.Sprintf("fnpop-%d-%d", n.number, n.refs)
+	fmt.Fprintf(&r.out, `<sup class="footnote-ref" id="%s"><button type="button" class="footnote-button" popovertarget="%s">%d</button></sup>`, id, popover, n.number)
+	fmt.Fprintf(&r.out, `<span class="footnote-popover" id="%s" popover>%s</span>`, popover, n.html)
+}
+
+// section returns the list of notes with links back to their references.
+func (r *renderer) section() string {
+	var b strings.Builder
+	b.WriteString(`<section class="footnotes`)
+	if r.opts.Class != "" {
+		b.WriteString(" " + html.EscapeString(r.opts.Class))
+	}
+	b.WriteString(`" role="doc-endnotes">` + "\n<ol>\n")
+	for _, n := range r.notes {
+		fmt.Fprintf(&b, `<li id="fn-%d">%s`, n.number, n.html)
+		for i := 1; i <= n.refs; i++ {
+			fmt.Fprintf(&b, ` <a href="#%s" class="footnote-backref" role="doc-backlink">↩`, refID(n.number, i))
+			if n.refs > 1 {
+				fmt.Fprintf(&b, "<sup>%d</sup>", i)
+			}
+			b.WriteString("</a>")
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ol>\n</section>")
+	return b.String()
+}
+
+// lastIndex returns the index of the last tag in tags, or -1.
+func lastIndex(tags []string, tag string) int {
+	for i := len(tags) - 1; i >= 0; i-- {
+		if tags[i] == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// refID is the id of the i-th reference to note number: "fnref-3", then
+// "fnref-3-2" and so on.
+func refID(number, i int) string {
+	if i == 1 {
+		return fmt.Sprintf("fnref-%d", number)
+	}
+	return fmt.Sprintf("fnref-%d-%d", number, i)
+}
@@ -0,0 +1,157 @@
+package footnotes
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+const (
+	ref1 = `<sup class="footnote-ref" id="fnref-1"><a href="#fn-1" role="doc-noteref">1</a></sup>`
+	ref2 = `<sup class="footnote-ref" id="fnref-2"><a href="#fn-2" role="doc-noteref">2</a></sup>`
+)
+
+// note1 is the entry of note 1, referenced once, in the list of notes.
+func note1(html string) string {
+	return `<li id="fn-1">` + html + ` <a href="#fnref-1" class="footnote-backref" role="doc-backlink">↩</a></li>`
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		body     string   // Expected output before the list of notes
+		notes    []string // Entries expected in the list
+		problems int
+	}{
+		{
+			name: "no markers",
+			in:   `<p>Nothing [to] see</p>`,
+			body: `<p>Nothing [to] see</p>`,
+		},
+		{
+			name:  "translator's note",
+			in:    `<p>Mamaru-san[TN: An honorific.] bowed.</p>`,
+			body:  `<p>Mamaru-san` + ref1 + ` bowed.</p>`,
+			notes: []string{note1("An honorific.")},
+		},
+		{
+			name:  "translator's note spellings",
+			in:    `<p>a[T/N: x] b[N. del T.: y]</p>`,
+			body:  `<p>a` + ref1 + ` b` + ref2 + `</p>`,
+			notes: []string{note1("x")},
+		},
+		{
+			name:  "reference and definition",
+			in:    `<p>The katana[^blade].</p><p>[^blade]: A <em>single-edged</em> sword.</p>`,
+			body:  `<p>The katana` + ref1 + `.</p>`,
+			notes: []string{note1("A <em>single-edged</em> sword.")},
+		},
+		{
+			name:     "undefined reference",
+			in:       `<p>The katana[^blade].</p>`,
+			body:     `<p>The katana[^blade].</p>`,
+			problems: 1,
+		},
+		{
+			name:     "unused definition",
+			in:       `<p>Text.</p><p>[^blade]: A sword.</p>`,
+			body:     `<p>Text.</p>`,
+			problems: 1,
+		},
+		{
+			name:     "unclosed note",
+			in:       `<p>a [TN: b <em>c</em></p><p>d</p>`,
+			body:     `<p>a [TN: b <em>c</em></p><p>d</p>`,
+			problems: 1,
+		},
+		{
+			name: "markers in code",
+			in:   `<p><code>a[TN: b]</code></p>`,
+			body: `<p><code>a[TN: b]</code></p>`,
+		},
+		{
+			name:  "note spanning inline tags",
+			in:    `<p>a [TN: b <em>c</em> d] e</p>`,
+			body:  `<p>a ` + ref1 + ` e</p>`,
+			notes: []string{note1("b <em>c</em> d")},
+		},
+		{
+			name:  "note ending inside a tag it opened",
+			in:    `<p>A [TN: starts <em>here] and</em> ends</p>`,
+			body:  `<p>A ` + ref1 + ` and ends</p>`,
+			notes: []string{note1("starts <em>here</em>")},
+		},
+		{
+			name:  "note ending inside nested tags it opened",
+			in:    `<p>A [TN: <b>x <i>y] z</i></b> w</p>`,
+			body:  `<p>A ` + ref1 + ` z w</p>`,
+			notes: []string{note1("<b>x <i>y</i></b>")},
+		},
+		{
+			name:  "note closing a tag opened before it",
+			in:    `<p><em>A [TN: b</em> c] d</p>`,
+			body:  `<p><em>A ` + ref1 + `</em> d</p>`,
+			notes: []string{note1("b c")},
+		},
+		{
+			name:  "note left open at the end of a block",
+			in:    `<p>A [TN: <em>b]</p><p>c</em> <em>d</em></p>`,
+			body:  `<p>A ` + ref1 + `</p><p>c</em> <em>d</em></p>`,
+			notes: []string{note1("<em>b</em>")},
+		},
+		{
+			name:     "unclosed note across inline tags is restored as written",
+			in:       `<p><em>A [TN: b</em> <i>c</p>`,
+			body:     `<p><em>A [TN: b</em> <i>c</p>`,
+			problems: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, problems := Render(template.HTML(tt.in), Options{})
+			got := string(out)
+			body, list, _ := strings.Cut(got, `<section class="footnotes"`)
+			if body != tt.body {
+				t.Errorf("body:\n got %s\nwant %s", body, tt.body)
+			}
+			for _, note := range tt.notes {
+				if !strings.Contains(list, note) {
+					t.Errorf("notes lack %s:\n%s", note, list)
+				}
+			}
+			if len(tt.notes) == 0 && list != "" {
+				t.Errorf("unexpected notes:\n%s", list)
+			}
+			if len(problems) != tt.problems {
+				t.Errorf("problems = %q, want %d", problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestRenderPopovers(t *testing.T) {
+	out, problems := Render(`<p>A [TN: starts <em>here] and</em> ends</p>`, Options{Popovers: true, Class: "content"})
+	if len(problems) > 0 {
+		t.Fatalf("problems = %q", problems)
+	}
+	want := `<p>A <sup class="footnote-ref" id="fnref-1"><button type="button" class="footnote-button" popovertarget="fnpop-1-1">1</button></sup>` +
+		`<span class="footnote-popover" id="fnpop-1-1" popover>starts <em>here</em></span> and ends</p>` +
+		`<section class="footnotes content" role="doc-endnotes">`
+	if got := string(out); !strings.HasPrefix(got, want) {
+		t.Errorf("got\n%s\nwant prefix\n%s", got, want)
+	}
+}
+
+func TestRenderRepeatedReference(t *testing.T) {
+	out, _ := Render(`<p>a[^n] b[^n]</p><p>[^n]: Note.</p>`, Options{})
+	got := string(out)
+	for _, want := range []string{
+		`id="fnref-1"`, `id="fnref-1-2"`,
+		`<a href="#fnref-1-2" class="footnote-backref" role="doc-backlink">↩<sup>2</sup></a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %s:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package formatter
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The rules below are those of the publisher's HtmlConverter, which wrote the
+// content_html and content_bulma columns:
+//
+//   - a line reading "Chapter 12" becomes a heading;
+//   - a line with text in parentheses is dialogue and becomes a quote;
+//   - a footnote definition ("[^1]: …") is a paragraph of its own, so the
+//     footnotes package can find it (this one is not the converter's);
+//   - other lines are joined into paragraphs with <br />, a blank line ending
+//     the paragraph;
+//   - character names are highlighted wherever they appear.
+//
+// Unlike the converter, the text is HTML-escaped, and of two names starting at
+// the same place the longer one is highlighted ("Saga Ferdio", not "Saga").
+
+var (
+	chapterRegex  = regexp.MustCompile(`^Chapter (\d+)$`)
+	dialogueRegex = regexp.MustCompile(`^\s*.*\(.*\)\s*$`)
+	footnoteRegex = regexp.MustCompile(`^\[\^[^\]\s]+\]:`)
+)
+
+// Style sets the tags and classes a chapter is written with.
+type Style struct {
+	SectionClass   string
+	ContainerClass string
+	ChapterTag     string
+	ChapterClass   string
+	DialogueTag    string
+	DialogueClass  string
+	NameTag        string
+	NameClass      string
+	ParagraphTag   string
+	ParagraphClass string
+	UseWrapper     bool // Wrap the chapter in <section><div>…</div></section>
+}
+
+// Plain is the style of the content_html column: bare tags, no wrapper.
+var Plain = Style{
+	ChapterTag:   "h2",
+	DialogueTag:  "blockquote",
+	NameTag:      "strong",
+	ParagraphTag: "p",
+}
+
+// Styled is the style of the content_bulma column.
+var Styled = Style{
+	SectionClass:   "section",
+	ContainerClass: "container",
+	ChapterTag:     "h2",
+	ChapterClass:   "title is-3",
+	DialogueTag:    "blockquote",
+	DialogueClass:  "notification is-light",
+	NameTag:        "span",
+	NameClass:      "tag is-info",
+	ParagraphTag:   "p",
+	ParagraphClass: "content",
+	UseWrapper:     true,
+}
+
+// Styles are the styles variants can name in their "format" setting.
+var Styles = map[string]Style{
+	"plain":  Plain,
+	"styled": Styled,
+}
+
+// Lookup returns the style called name.
+func Lookup(name string) (Style, error) {
+	style, ok := Styles[name]
+	if !ok {
+		known := make([]string, 0, len(Styles))
+		for n := range Styles {
+			known = append(known, n)
+		}
+		sort.Strings(known)
+		return Style{}, fmt.Errorf("unknown format style %q (known: %s)", name, strings.Join(known, ", "))
+	}
+	return style, nil
+}
+
+// Formatter turns the plain text of chapters into HTML.
+type Formatter struct {
+	names *regexp.Regexp // nil when there are no names to highlight
+}
+
+// New returns a Formatter highlighting the given character names.
+func New(names []string) *Formatter {
+	sorted := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			sorted = append(sorted, regexp.QuoteMeta(html.EscapeString(name)))
+		}
+	}
+	if len(sorted) == 0 {
+		return &Formatter{}
+	}
+	// Longest first, so the regexp prefers "Saga Ferdio" over "Saga"
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	// Names must stand alone; \b only knows ASCII letters, so "Dragón" needs the
+	// explicit classes. The surrounding characters are matched outside group 1.
+	pattern := `(?:^|[^\p{L}\p{N}_])(` + strings.Join(sorted, "|") + `)(?:[^\p{L}\p{N}_]|$)`
+	return &Formatter{names: regexp.MustCompile(pattern)}
+}
+
+// Format converts text to HTML in the given style. Blank text gives "".
+func (f *Formatter) Format(text string, style Style) template.HTML {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	var paragraph []string
+	closeParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		b.WriteString("<" + style.ParagraphTag + classAttr(style.ParagraphClass) + ">")
+		b.WriteString(strings.Join(paragraph, "<br />\n"))
+		b.WriteString("</" + style.ParagraphTag + ">\n")
+		paragraph = paragraph[:0]
+	}
+
+	for _, rawLine := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(rawLine)
+		switch {
+		case chapterRegex.MatchString(line):
+			closeParagraph()
+			b.WriteString("<" + style.ChapterTag + classAttr(style.ChapterClass) + ">" + html.EscapeString(line) + "</" + style.ChapterTag + ">\n")
+		case footnoteRegex.MatchString(line):
+			closeParagraph()
+			paragraph = append(paragraph, f.highlight(html.EscapeString(line), style))
+			closeParagraph()
+		case dialogueRegex.MatchString(rawLine):
+			closeParagraph()
+			quote := f.highlight(html.EscapeString(strings.TrimLeftFunc(rawLine, unicode.IsSpace)), style)
+			b.WriteString("<" + style.DialogueTag + classAttr(style.DialogueClass) + ">" + quote + "</" + style.DialogueTag + ">\n")
+		case line == "":
+			closeParagraph()
+		default:
+			paragraph = append(paragraph, f.highlight(html.EscapeString(rawLine), style))
+		}
+	}
+	closeParagraph()
+
+	out := strings.TrimRightFunc(b.String(), unicode.IsSpace)
+	if style.UseWrapper {
+		out = "<section" + classAttr(style.SectionClass) + ">\n<div" + classAttr(style.ContainerClass) + ">\n" + out + "\n</div>\n</section>"
+	}
+	return template.HTML(out)
+}
+
+// highlight wraps the character names in the escaped line in the style's name tag.
+func (f *Formatter) highlight(line string, style Style) string {
+	var b strings.Builder
+	pos := 0
+	f.eachName(line, func(start, end int) {
+		b.WriteString(line[pos:start])
+		b.WriteString("<" + style.NameTag + classAttr(style.NameClass) + ">" + line[start:end] + "</" + style.NameTag + ">")
+		pos = end
+	})
+	b.WriteString(line[pos:])
+	return b.String()
+}
+
+// Find returns the names occurring in text (HTML-escaped text without tags),
+// each once, in order of first appearance. The text is decoded and escaped
+// again the way the names are, so stored HTML that leaves an apostrophe bare
+// still matches "D'Artagnan".
+func (f *Formatter) Find(text string) []string {
+	text = html.EscapeString(html.UnescapeString(text))
+	var found []string
+	seen := make(map[string]bool)
+	f.eachName(text, func(start, end int) {
+		if name := html.UnescapeString(text[start:end]); !seen[name] {
+			seen[name] = true
+			found = append(found, name)
+		}
+	})
+	return found
+}
+
+// eachName calls fn with the bounds of every name in the escaped text.
+func (f *Formatter) eachName(text string, fn func(start, end int)) {
+	if f.names == nil {
+		return
+	}
+	for pos := 0; ; {
+		m := f.names.FindStringSubmatchIndex(text[pos:])
+		if m == nil {
+			return
+		}
+		fn(pos+m[2], pos+m[3])
+		// Resume right after the name: the character matched after it may
+		// precede the next one
+		pos += m[3]
+	}
+}
+
+// classAttr returns ` class="…"`, or "" for no class.
+func classAttr(class string) string {
+	if class == "" {
+		return ""
+	}
+	return ` class="` + html.EscapeString(class) + `"`
+}
@@ -0,0 +1,30 @@
+package formatter
+
+// DefaultNames are the character names highlighted by the publisher's
+// HtmlConverter, kept here so reformatted chapters match the stored HTML.
+var DefaultNames = []string{
+	"Saga", "Abuelo de Silk", "Acechador de Sombras", "Anciano Elran", "Anciano de los elfos", "Bandido",
+	"Baron Gato", "Barry", "Barón Gato Tsomi", "Benwood", "Besio Salas", "Biblion", "Big Rod", "Bran",
+	"Bran Crowder", "Butlers", "Caballero sin nombre", "Camilla", "Cangrejo de Acero", "Capitán Jules",
+	"Capitán Jules Stien", "Captain Jules", "Carmine", "Cazador Sombrío", "Clover", "Conde de Crowder",
+	"Condesa Crowder", "Cronie A", "Dimwit", "Dorcas", "Dormer", "Dragón", "Dryad", "Dulcus",
+	"El mago sin nombre", "Elder Elran", "Elfos Oscuros", "Elran", "Feldio", "Ferdio", "Freia",
+	"Full Bound", "Fullama", "Glad Shi-Im", "Glad-Shi-Im", "Gold", "Gopro-kun", "Gopro-kun G",
+	"Gruntsblow", "Guardias Salmutarianos", "Guildmaster", "Huevo Andante", "Ilwen", "Ilwen Pearlwood",
+	"Jamie", "King Vordan", "Lalm", "Lefty Hand", "Lizard", "Loge", "Lord Feldio", "Lucent", "Lun",
+	"Lung", "Líder de los bandidos", "Maid", "Maje", "Malignant", "Malignant the Defiler", "Mamal-san",
+	"Mamaru", "Mamaru-san", "Manauela", "Manuela", "Mapara", "Marignant", "Marina", "Marona", "Marqués",
+	"Marqués Bedivoir", "Marqués de Bedivere", "Mastoma", "Mastoma-sama", "Mayordomo", "Mazaara",
+	"Mejaluna", "Mieche", "Miembros de Clover", "Miriam", "Mob A", "Mobs A-D", "Moriah",
+	"Mujer welmeriana", "Nene", "Nibelun", "Nibelung", "One Gold", "Padre de Ilwen", "Pale Undead King",
+	"Patriarca", "Persephone", "Personal del gremio", "Perséfone", "Prince Mastoma", "Prince Rahma",
+	"Prince Rahuma", "Príncipe Mastoma", "Príncipe Rahma", "Príncipe Salmutaria", "Rafael", "Rahma",
+	"Rahuma", "Rain", "Reynise", "Rey", "Rey Pálido No Muerto", "Rey Vaudan", "Rey Vincent",
+	"Rey Vincent V", "Rey Vincent V de Wellmeria", "Rey Vordan", "Rey de Welmeria", "Rey del Trono",
+	"Rooge", "Saga Ferdio", "Scordia", "Sensei", "Shadow Stalker", "Shadow Stalkers", "Silk",
+	"Silk Amberwood", "Simon", "Simon Barkley", "Sir Feldio", "Sirviente sombrío de Ilwen", "Skordia",
+	"Sohar", "Soldado", "Soldado Elfo Oscuro", "Steel Crab", "Stinger Joe", "Thunder Pike",
+	"Thunderpike", "Trent", "Tymus", "Tío Saga", "Uno Dorado", "Vibrion", "Viktor", "Vincent",
+	"Vincent V", "Visconde Boardman", "Vizconde Boardman", "Vordan", "Walkers", "Wellmeria", "Wilson",
+	"Yuke", "Yuke Feldio", "Yuki", "Yuki Ferdio", "Zaccardo", "Zagnar", "Zarnag",
+}
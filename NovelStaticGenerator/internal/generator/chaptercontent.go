@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/anchors"
+	"NovelStaticGenerator/internal/footnotes"
+	"NovelStaticGenerator/internal/formatter"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/sanitize"
+	"NovelStaticGenerator/internal/typography"
+	"NovelStaticGenerator/internal/variants"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"time"
+)
+
+// contentKey identifies the content of one chapter in one variant.
+type contentKey struct {
+	chapter *models.Chapter
+	variant string
+}
+
+// Removal records what sanitizing took out of a chapter's stored content in
+// one variant.
+type Removal struct {
+	Novel   *models.Novel
+	Chapter *models.Chapter
+	Variant *models.Variant
+	Report  sanitize.Report
+}
+
+func (r Removal) String() string {
+	return fmt.Sprintf("%s V%d C%d [%s]: %s", r.Novel.Name, r.Chapter.VolumeNumber, r.Chapter.ChapterNumber, r.Variant.Name, r.Report)
+}
+
+// Removals lists what sanitizing removed from the chapters, in reading order.
+// It is filled by Prepare.
+func (sg *SiteGenerator) Removals() []Removal {
+	return sg.removals
+}
+
+// prepareContent works out the HTML of every chapter in every variant once:
+// content_plain run through the formatter for variants with a format, the
+// stored column otherwise, sanitized with the variant's policy when
+// sg.Sanitize is set. Formatter output escapes all text and needs no
+// sanitizing; stored HTML comes from imports and may contain anything.
+// finishContent then turns the footnote markers into notes, adds the anchors
+// and fixes the punctuation, and each chapter gets the table of contents of its
+// primary variant.
+func (sg *SiteGenerator) prepareContent(novels []*models.Novel) {
+	start := time.Now()
+	sg.contents = make(map[contentKey]template.HTML)
+	sg.removals = nil
+	sanitizers := make(map[string]*sanitize.Sanitizer, len(sg.Variants))
+	for _, variant := range sg.Variants {
+		sanitizers[variant.Name] = sanitize.New(variant.Sanitize)
+	}
+
+	for _, novel := range novels {
+		typo := typography.New(sg.Typography, novel.Lang)
+		for _, chapter := range novel.Chapters {
+			if chapter == nil {
+				continue
+			}
+			for i, variant := range sg.Variants {
+				content, toc, problems := finishContent(sg.variantContent(novel, chapter, variant, sanitizers[variant.Name]), variant, typo)
+				if i == 0 {
+					chapter.TOC = toc
+					// The variants share their markers; report them once
+					for _, problem := range problems {
+						slog.Warn("footnote problem", "novel", novel.Name, "volume", chapter.VolumeNumber, "chapter", chapter.ChapterNumber, "problem", problem)
+					}
+				}
+				sg.contents[contentKey{chapter, variant.Name}] = content
+			}
+		}
+	}
+	slog.Debug("prepared chapter content", "chapters", len(sg.contents), "sanitized", sg.Sanitize, "typography", sg.Typography, "with_removals", len(sg.removals), "duration", time.Since(start))
+}
+
+// finishContent renders the footnotes of a chapter's HTML in variant, gives its
+// headings and scene breaks their anchors and then applies typo to the text and
+// to the titles of the table of contents. Typography comes last because it
+// changes what the other two read: French spacing puts a non-breaking space
+// in "[TN: …]" and "[^1]:", and dashes turn the "---" of a scene break into
+// "—". It returns the footnote problems found.
+func finishContent(content template.HTML, variant *models.Variant, typo *typography.Transformer) (template.HTML, []*models.TOCEntry, []string) {
+	content, problems := footnotes.Render(content, footnotes.Options{
+		Popovers: variant.Footnotes == variants.FootnotePopovers,
+		Class:    variant.Params["footnotes_class"],
+	})
+	content, toc := anchors.Add(content)
+	for _, entry := range toc {
+		entry.Title = typo.Text(entry.Title)
+	}
+	return typo.Apply(content), toc, problems
+}
+
+// variantContent returns the chapter's HTML in variant before anchors are added.
+func (sg *SiteGenerator) variantContent(novel *models.Novel, chapter *models.Chapter, variant *models.Variant, sanitizer *sanitize.Sanitizer) template.HTML {
+	if variant.Format != "" {
+		style, _ := formatter.Lookup(variant.Format) // Checked by variants.Validate
+		return sg.Formatter.Format(chapter.Plain, style)
+	}
+	stored := chapter.Content[variant.Content]
+	if !sg.Sanitize {
+		return stored
+	}
+	clean, report := sanitizer.Sanitize(string(stored))
+	if len(report) > 0 {
+		sg.removals = append(sg.removals, Removal{Novel: novel, Chapter: chapter, Variant: variant, Report: report})
+	}
+	return template.HTML(clean) // Sanitized: only allowed tags, attributes and URLs remain
+}
+
+// chapterContent returns the chapter's HTML in variant, as prepared by
+// prepareContent.
+func (sg *SiteGenerator) chapterContent(chapter *models.Chapter, variant *models.Variant) template.HTML {
+	return sg.contents[contentKey{chapter, variant.Name}]
+}
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/typography"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestFinishContentFootnotesWithTypography(t *testing.T) {
+	rules, err := typography.ParseRules("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := template.HTML(`<h2>"The" duel</h2>` +
+		`<p>Mamaru-san[TN: An honorific.] drew the katana[^blade]...</p>` +
+		`<p>---</p>` +
+		`<p>[^blade]: A single-edged sword.</p>`)
+
+	for _, lang := range []string{"en", "fr"} {
+		t.Run(lang, func(t *testing.T) {
+			got, toc, problems := finishContent(content, &models.Variant{Name: "plain"}, typography.New(rules, lang))
+			if len(problems) > 0 {
+				t.Errorf("problems = %q, want none", problems)
+			}
+			s := string(got)
+			for _, want := range []string{
+				`<li id="fn-1">An honorific. <a href="#fnref-1"`,
+				`<li id="fn-2">A single-edged sword. <a href="#fnref-2"`,
+				`href="#fn-1" role="doc-noteref">1</a></sup> drew the katana`,
+				`href="#fn-2" role="doc-noteref">2</a></sup>…</p>`,
+				`<p id="scene-2">—</p>`,
+			} {
+				if !strings.Contains(s, want) {
+					t.Errorf("output lacks %q:\n%s", want, s)
+				}
+			}
+			if strings.Contains(s, "[") {
+				t.Errorf("a marker was left in the output:\n%s", s)
+			}
+			if len(toc) != 2 || toc[0].ID != "the-duel" || toc[1].ID != "scene-2" {
+				t.Fatalf("toc = %+v, want the heading and one scene break", toc)
+			}
+			wantTitle := map[string]string{"en": "“The” duel", "fr": "«\u202fThe\u202f» duel"}[lang]
+			if toc[0].Title != wantTitle {
+				t.Errorf("heading title = %q, want %q", toc[0].Title, wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/formatter"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/typography"
+	"NovelStaticGenerator/internal/utils"
+	"fmt"
+	"html"
+	"html/template"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	// nameElementRegex matches a highlighted name as the formatter (and the
+	// publisher before it) writes one: <strong>Name</strong> in plain chapters,
+	// <span class="tag is-info">Name</span> in styled ones. Group 1 and 4 are
+	// the opening and closing tag names, group 3 the escaped text.
+	nameElementRegex = regexp.MustCompile(`<(strong|span)(\s[^>]*)?>([^<]+)</(strong|span)>`)
+
+	// tagRegex matches an HTML tag, to reduce chapter HTML to its text.
+	tagRegex = regexp.MustCompile(`<[^>]*>`)
+)
+
+// characterNames lists the names and aliases of the glossary characters.
+func (sg *SiteGenerator) characterNames() []string {
+	var names []string
+	for _, character := range sg.Characters {
+		names = append(names, character.Name)
+		names = append(names, character.Aliases...)
+	}
+	return names
+}
+
+// organizeCharacters assigns output paths to the glossary characters and finds
+// the chapters naming each of them in the text of their primary variant. It
+// runs after organizeChapters, which puts the chapters in reading order.
+func (sg *SiteGenerator) organizeCharacters(novels []*models.Novel) error {
+	sg.characters = make(map[string]*models.Character)
+	sorted := append([]*models.Character{}, sg.Characters...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	slugs := make(map[string]bool)
+	for _, character := range sorted {
+		character.Slug = characterSlug(character, slugs)
+		character.Path = sg.URLs.CharacterPath(character)
+		character.URL = sg.URLs.Link(character.Path)
+		character.Appearances = nil
+		if err := sg.claim(character.Path, fmt.Sprintf("character '%s'", character.Name)); err != nil {
+			return err
+		}
+		for _, name := range append([]string{character.Name}, character.Aliases...) {
+			if other, ok := sg.characters[name]; ok && other != character {
+				slog.Warn("character name is used by two characters, linking it to the first", "name", name, "character", character.Name, "first", other.Name)
+				continue
+			}
+			sg.characters[name] = character
+		}
+	}
+	if len(sorted) == 0 {
+		return nil
+	}
+	if err := sg.claim(sg.URLs.CharactersPath(), "character glossary"); err != nil {
+		return err
+	}
+	sg.site.Characters = sorted
+	sg.site.CharactersURL = sg.URLs.Link(sg.URLs.CharactersPath())
+
+	finders := make(map[string]*formatter.Formatter) // By novel language
+	for _, novel := range novels {
+		finder, ok := finders[novel.Lang]
+		if !ok {
+			finder = sg.characterFinder(typography.New(sg.Typography, novel.Lang))
+			finders[novel.Lang] = finder
+		}
+		for _, chapter := range novel.Chapters {
+			if chapter == nil {
+				continue
+			}
+			chapter.Characters = nil
+			text := tagRegex.ReplaceAllString(string(sg.chapterContent(chapter, sg.Variants[0])), " ")
+			for _, name := range finder.Find(text) {
+				character := sg.characters[name]
+				if slices.Contains(chapter.Characters, character) {
+					continue // Named by an alias too
+				}
+				chapter.Characters = append(chapter.Characters, character)
+				character.Appearances = append(character.Appearances, chapter)
+			}
+			sort.SliceStable(chapter.Characters, func(i, j int) bool { return chapter.Characters[i].Name < chapter.Characters[j].Name })
+		}
+	}
+	return nil
+}
+
+// characterSlug returns the slug of character's page: the slug of its name, or
+// character-<ID> when the name has nothing a slug can keep (such as a name in
+// Chinese). A slug already in slugs, as "Marques" takes "Marqués"'s, gets
+// -<ID> added. The slug returned is added to slugs.
+func characterSlug(character *models.Character, slugs map[string]bool) string {
+	slug := utils.Slugify(character.Name)
+	if slug == utils.UntitledSlug {
+		slug = fmt.Sprintf("character-%d", character.ID)
+	}
+	if slugs[slug] {
+		slug = fmt.Sprintf("%s-%d", slug, character.ID)
+	}
+	slugs[slug] = true
+	return slug
+}
+
+// characterFinder returns a formatter finding the glossary names in chapter
+// text that went through typo. The names are written as typo writes them, so
+// "D'Artagnan" is found as "D’Artagnan", and those spellings are added to
+// sg.characters for Find's results and for linkCharacters.
+func (sg *SiteGenerator) characterFinder(typo *typography.Transformer) *formatter.Formatter {
+	var names []string
+	for _, name := range sg.characterNames() {
+		typed := typo.Text(name)
+		if _, ok := sg.characters[typed]; !ok {
+			sg.characters[typed] = sg.characters[name]
+		}
+		names = append(names, typed)
+	}
+	return formatter.New(names)
+}
+
+// linkCharacters wraps the highlighted names of glossary characters in the
+// chapter content written at pagePath in links to their pages.
+func (sg *SiteGenerator) linkCharacters(content template.HTML, pagePath string) template.HTML {
+	if len(sg.characters) == 0 {
+		return content
+	}
+	return template.HTML(nameElementRegex.ReplaceAllStringFunc(string(content), func(element string) string {
+		m := nameElementRegex.FindStringSubmatch(element)
+		if m[1] != m[4] {
+			return element
+		}
+		character, ok := sg.characters[strings.TrimSpace(html.UnescapeString(m[3]))]
+		if !ok {
+			return element
+		}
+		href := html.EscapeString(sg.URLs.RelURL(pagePath, character.URL))
+		return `<a class="character-link" href="` + href + `">` + element + `</a>`
+	}))
+}
+
+// generateCharacterPages writes the glossary index and one page per character.
+func (sg *SiteGenerator) generateCharacterPages() error {
+	if len(sg.site.Characters) == 0 {
+		return nil
+	}
+	if err := sg.renderPage("characters", sg.URLs.CharactersPath(), sg.Lang, sg.charactersData()); err != nil {
+		return fmt.Errorf("character glossary: %w", err)
+	}
+	for _, character := range sg.site.Characters {
+		if err := sg.renderPage("character", character.Path, sg.Lang, sg.characterData(character)); err != nil {
+			return fmt.Errorf("character '%s': %w", character.Name, err)
+		}
+	}
+	slog.Info("generated character glossary", "characters", len(sg.site.Characters))
+	return nil
+}
+
+// charactersData builds the data for the glossary index.
+func (sg *SiteGenerator) charactersData() models.CharactersPageData {
+	return models.CharactersPageData{
+		Site:         sg.site,
+		Characters:   sg.site.Characters,
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(sg.URLs.CharactersPath()),
+		Lang:         sg.Lang,
+	}
+}
+
+// characterData builds the data for a character page.
+func (sg *SiteGenerator) characterData(character *models.Character) models.CharacterPageData {
+	return models.CharacterPageData{
+		Site:         sg.site,
+		Character:    character,
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(character.Path),
+		Lang:         sg.Lang,
+	}
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/typography"
+	"NovelStaticGenerator/internal/urls"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+// characterSite returns a generator for one novel in lang whose chapters have
+// the given stored HTML, with characters as its glossary.
+func characterSite(t *testing.T, lang string, rules string, characters []*models.Character, contents ...string) *SiteGenerator {
+	t.Helper()
+	urlBuilder, err := urls.NewBuilder("", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	typo, err := typography.ParseRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	novel := &models.Novel{ID: 1, Name: "Novel", Lang: lang, Volumes: []*models.Volume{{ID: 1, NovelID: 1, Number: 1}}}
+	var chapters []*models.Chapter
+	for i, content := range contents {
+		chapters = append(chapters, &models.Chapter{
+			ID: i + 1, NovelID: 1, VolumeID: 1, NovelName: novel.Name, ChapterNumber: i + 1, VolumeNumber: 1,
+			Content: map[string]template.HTML{"content_html": template.HTML(content)},
+		})
+	}
+	sg := NewSiteGenerator([]*models.Novel{novel}, chapters, "", nil, nil, urlBuilder)
+	sg.Characters = characters
+	sg.Typography = typo
+	if _, err := sg.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	return sg
+}
+
+func TestCharacterSlugs(t *testing.T) {
+	characters := []*models.Character{
+		{ID: 1, Name: "李雷"},
+		{ID: 2, Name: "Ана"},
+		{ID: 3, Name: "Marqués"},
+		{ID: 4, Name: "Marques"},
+		{ID: 5, Name: "Saga"},
+	}
+	characterSite(t, "es", "none", characters, "<p>Saga</p>")
+	want := map[string]string{
+		"李雷":      "characters/character-1.html",
+		"Ана":     "characters/character-2.html",
+		"Marques": "characters/marques.html",
+		"Marqués": "characters/marques-3.html",
+		"Saga":    "characters/saga.html",
+	}
+	for _, character := range characters {
+		if character.Path != want[character.Name] {
+			t.Errorf("%s: path = %q, want %q", character.Name, character.Path, want[character.Name])
+		}
+	}
+}
+
+func TestCharacterAppearancesWithApostrophes(t *testing.T) {
+	for _, rules := range []string{"none", "all"} {
+		t.Run(rules, func(t *testing.T) {
+			characters := []*models.Character{
+				{ID: 1, Name: "D'Artagnan"},
+				{ID: 2, Name: "Porthos"},
+			}
+			sg := characterSite(t, "en", rules, characters,
+				`<p><strong>D'Artagnan</strong> met <strong>Porthos</strong>.</p>`,
+				`<p>"D&#39;Artagnan!" said Porthos.</p>`,
+				`<p>Nobody here.</p>`,
+			)
+			for _, character := range characters {
+				if len(character.Appearances) != 2 {
+					t.Errorf("%s appears in %d chapters, want 2", character.Name, len(character.Appearances))
+				}
+			}
+
+			chapter := sg.Chapters[0]
+			linked := string(sg.linkCharacters(sg.chapterContent(chapter, sg.Variants[0]), chapter.Path))
+			for _, character := range characters {
+				if !strings.Contains(linked, `href="`+sg.URLs.RelURL(chapter.Path, character.URL)+`"`) {
+					t.Errorf("%s is not linked in %s", character.Name, linked)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/content"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/templatefuncs"
+	"NovelStaticGenerator/internal/theme"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+)
+
+// requiredTemplates are the page templates a build always renders.
+// Chapter templates are required per variant.
+var requiredTemplates = []string{"index", "novel", "volume", "characters", "character", "stats", "search", content.DefaultLayout}
+
+// Problem is one template failure found by CheckTemplates.
+type Problem struct {
+	Template string // Page template name, e.g. "chapter" or "novels/<slug>/chapter"
+	Case     string // The fixture the template was rendered with
+	Err      error
+}
+
+func (p Problem) String() string {
+	if p.Case == "" {
+		return fmt.Sprintf("%s: %v", p.Template, p.Err)
+	}
+	return fmt.Sprintf("%s [%s]: %v", p.Template, p.Case, p.Err)
+}
+
+// CheckTemplates executes every loaded page template against the generator's
+// novels, chapters and pages (normally fixture data) and returns the problems
+// found: templates a build needs but the theme lacks, execution errors such as
+// fields that do not exist or nil pointers, and pages too short to have used
+// _base.html. Nothing is written to the output directory.
+//
+// Templates are matched to data by their base name: index, novel, volume,
+// characters, character, stats, search and the variants' chapter templates get
+// their usual data, and any other template is treated as a content page
+// layout. A per-novel override (novels/<slug>/novel and the like) is checked
+// only with the novel it overrides.
+func (sg *SiteGenerator) CheckTemplates() ([]Problem, error) {
+	novels, err := sg.Prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	required := append([]string{}, requiredTemplates...)
+	for _, variant := range sg.Variants {
+		if !slices.Contains(required, variant.Template) {
+			required = append(required, variant.Template)
+		}
+	}
+	for _, name := range required {
+		if sg.Templates[name] == nil {
+			problems = append(problems, Problem{Template: name, Err: fmt.Errorf("theme has no %s.html page template", name)})
+		}
+	}
+
+	names := make([]string, 0, len(sg.Templates))
+	for name := range sg.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		slug, override := theme.OverrideSlug(name)
+		forNovel := func(novel *models.Novel) bool { return !override || novel.Slug == slug }
+		check := func(fixture, relPath, lang string, data any) {
+			out, err := sg.render(name, relPath, lang, data)
+			if err == nil && len(out) < minPageSize {
+				err = fmt.Errorf("output is only %d bytes, the page template probably does not use _base.html", len(out))
+			}
+			if err != nil {
+				problems = append(problems, Problem{Template: name, Case: fixture, Err: err})
+			}
+		}
+
+		switch base := path.Base(name); {
+		case base == "index":
+			check("all novels", sg.URLs.IndexPath(), sg.Lang, sg.indexData(novels))
+			check("no novels", sg.URLs.IndexPath(), sg.Lang, sg.indexData(nil))
+		case base == "novel":
+			for _, novel := range novels {
+				if !forNovel(novel) {
+					continue
+				}
+				check(novelCase(novel), novel.Path, novel.Lang, sg.novelData(novel))
+			}
+		case base == "volume":
+			for _, novel := range novels {
+				if !forNovel(novel) {
+					continue
+				}
+				for _, volume := range novel.Volumes {
+					check(fmt.Sprintf("volume %d (%d chapters) of %s", volume.Number, len(volume.Chapters), novelCase(novel)), volume.Path, novel.Lang, sg.volumeData(novel, volume))
+				}
+			}
+		case base == "characters":
+			check("all characters", sg.URLs.CharactersPath(), sg.Lang, sg.charactersData())
+			check("no characters", sg.URLs.CharactersPath(), sg.Lang, models.CharactersPageData{Site: sg.site, Variant: sg.Variants[0], SiteBasePath: sg.URLs.BasePath(sg.URLs.CharactersPath()), Lang: sg.Lang})
+		case base == "character":
+			for _, character := range sg.site.Characters {
+				check(fmt.Sprintf("character '%s' (%d aliases, %d appearances)", templatefuncs.Truncate(40, character.Name), len(character.Aliases), len(character.Appearances)), character.Path, sg.Lang, sg.characterData(character))
+			}
+		case base == "stats":
+			check("all novels", sg.URLs.StatsPath(), sg.Lang, sg.statsData(novels))
+			check("no novels", sg.URLs.StatsPath(), sg.Lang, sg.statsData(nil))
+		case base == "search":
+			check("search page", sg.URLs.SearchPath(), sg.Lang, sg.searchData())
+		case sg.isChapterTemplate(base):
+			for _, novel := range novels {
+				if !forNovel(novel) {
+					continue
+				}
+				for _, chapter := range novel.Chapters {
+					for _, variant := range sg.Variants {
+						if variant.Template != base {
+							continue
+						}
+						relPath, data := sg.chapterData(novel, chapter, variant)
+						check(chapterCase(novel, chapter, variant), relPath, novel.Lang, data)
+					}
+				}
+			}
+		default:
+			for _, page := range sg.Pages {
+				check(fmt.Sprintf("content page '%s'", page.Source), page.Path, page.Lang, sg.contentData(page))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// novelCase describes a novel fixture in a problem report.
+func novelCase(novel *models.Novel) string {
+	return fmt.Sprintf("novel '%s' (%d chapters)", templatefuncs.Truncate(40, novel.Name), len(novel.Chapters))
+}
+
+// isChapterTemplate reports whether some variant renders chapters with the
+// template named base.
+func (sg *SiteGenerator) isChapterTemplate(base string) bool {
+	for _, variant := range sg.Variants {
+		if variant.Template == base {
+			return true
+		}
+	}
+	return false
+}
+
+// chapterCase describes a chapter fixture in a problem report.
+func chapterCase(novel *models.Novel, chapter *models.Chapter, variant *models.Variant) string {
+	position := "chapter"
+	switch {
+	case chapter.PrevChapter == nil && chapter.NextChapter == nil:
+		position = "only chapter"
+	case chapter.PrevChapter == nil:
+		position = "first chapter"
+	case chapter.NextChapter == nil:
+		position = "last chapter"
+	}
+	return fmt.Sprintf("%s V%d C%d of %s, %s", position, chapter.VolumeNumber, chapter.ChapterNumber, novelCase(novel), variant.Name)
+}
@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"time"
+)
+
+// feedEntryLimit caps how many of the newest chapters go into each feed.
+const feedEntryLimit = 30
+
+// atomFeed is the root element of an Atom 1.0 feed.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Author  atomAuthor `xml:"author"`
+	Link    atomLink   `xml:"link"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// generateFeeds writes a site-wide feed.xml and a feed.xml per novel with the
+// most recently published chapters.
+func (sg *SiteGenerator) generateFeeds(novels []*models.Novel) error {
+	var all []*models.Chapter
+	authors := make(map[int]string, len(novels))
+	for _, novel := range novels {
+		all = append(all, novel.Chapters...)
+		authors[novel.ID] = novel.Author
+
+		feedPath := path.Join(novel.Slug, "feed.xml")
+		if err := sg.writeFeed(feedPath, novel.Name, novel.Path, novel.Chapters, authors); err != nil {
+			return err
+		}
+	}
+	return sg.writeFeed("feed.xml", "Novel Site", sg.URLs.IndexPath(), all, authors)
+}
+
+// writeFeed encodes one Atom feed for chapters (newest first) to feedPath.
+// alternatePath is the page the feed belongs to.
+func (sg *SiteGenerator) writeFeed(feedPath, title, alternatePath string, chapters []*models.Chapter, authors map[int]string) error {
+	newest := make([]*models.Chapter, len(chapters))
+	copy(newest, chapters)
+	sort.SliceStable(newest, func(i, j int) bool { return newest[i].CreatedAt.After(newest[j].CreatedAt) })
+	if len(newest) > feedEntryLimit {
+		newest = newest[:feedEntryLimit]
+	}
+
+	updated := latestUpdate(newest)
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed := atomFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      sg.URLs.CanonicalURL(feedPath),
+		Title:   title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: sg.URLs.CanonicalURL(feedPath), Rel: "self"},
+			{Href: sg.URLs.CanonicalURL(alternatePath), Rel: "alternate"},
+		},
+	}
+	for _, ch := range newest {
+		entryUpdated := ch.UpdatedAt
+		if entryUpdated.IsZero() {
+			entryUpdated = updated
+		}
+		entryTitle := fmt.Sprintf("%s - Vol. %d Ch. %d", ch.NovelName, ch.VolumeNumber, ch.ChapterNumber)
+		if ch.Title != "" {
+			entryTitle += ": " + ch.Title
+		}
+		author := authors[ch.NovelID]
+		if author == "" {
+			author = ch.NovelName
+		}
+		link := sg.URLs.CanonicalURL(ch.Path)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      link,
+			Title:   entryTitle,
+			Updated: entryUpdated.UTC().Format(time.RFC3339),
+			Author:  atomAuthor{Name: author},
+			Link:    atomLink{Href: link},
+		})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode feed '%s': %w", feedPath, err)
+	}
+	if err := sg.writeFile(feedPath, append([]byte(xml.Header), data...)); err != nil {
+		return err
+	}
+	slog.Debug("generated feed", "file", feedPath, "entries", len(feed.Entries))
+	return nil
+}
@@ -1,185 +1,294 @@
 package generator
 
 import (
+	"NovelStaticGenerator/internal/assets"
+	"NovelStaticGenerator/internal/content"
+	"NovelStaticGenerator/internal/formatter"
+	"NovelStaticGenerator/internal/i18n"
+	"NovelStaticGenerator/internal/models" // Adjust import path
+	"NovelStaticGenerator/internal/templatefuncs"
+	"NovelStaticGenerator/internal/theme"
+	"NovelStaticGenerator/internal/typography"
+	"NovelStaticGenerator/internal/urls"
+	"NovelStaticGenerator/internal/utils" // Adjust import path
+	"NovelStaticGenerator/internal/variants"
 	"bytes"
 	"fmt"
 	"html/template"
-	"io"
-	"log"
-	"NovelStaticGenerator/internal/models" // Adjust import path
-	"NovelStaticGenerator/internal/utils"  // Adjust import path
-	"os"
-	"path/filepath"
+	"log/slog"
 	"sort"
+	"time"
 )
 
 // SiteGenerator holds the state and configuration for the generation process.
 type SiteGenerator struct {
-	Chapters    []*models.Chapter
-	OutputDir   string
-	Templates   map[string]*template.Template
-	StaticDir   string // Path to the source static assets directory
+	Novels     []*models.Novel // Novel metadata with volumes; chapters are attached by organizeChapters
+	Chapters   []*models.Chapter
+	Pages      []*models.Page      // Standalone content pages (optional)
+	Characters []*models.Character // Character glossary (optional)
+	OutputDir  string
+	Output     Output // Where the site is written (default: files in OutputDir)
+	Templates  map[string]*template.Template
+	Assets     *assets.Manifest     // Processed theme static files, written into the output directory
+	URLs       *urls.Builder        // Computes output paths and links for every page
+	Lang       string               // Site language, used by novels and pages that set none
+	Catalog    *i18n.Catalog        // UI strings for the T template function
+	Variants   []*models.Variant    // Renderings of every chapter; the first is the primary one
+	Formatter  *formatter.Formatter // Formats content_plain for variants with a format (default: formatter.DefaultNames)
+
+	Sanitize      bool             // Strip stored chapter HTML each variant's policy does not allow
+	PurgeCSS      bool             // Drop stylesheet rules for classes no generated page uses
+	PurgeSafelist []string         // Classes (or "prefix-*" patterns) kept by the purge anyway
+	Search        bool             // Write the search index and the search page
+	Typography    typography.Rules // Punctuation fixes applied to chapter text in each novel's language
+	OnlyNovels    map[int]bool     // Novel IDs whose novel, volume and chapter pages are written (nil for all)
+
+	site    *models.Site      // Shared page data, built at the start of GenerateSite
+	claimed map[string]string // Output path -> description of the page writing it
+
+	characters map[string]*models.Character // Glossary characters by name and alias
+	contents   map[contentKey]template.HTML // Chapter HTML by variant, see prepareContent
+	removals   []Removal                    // What sanitizing took out, see prepareContent
 }
 
 // NewSiteGenerator creates a new generator instance.
-func NewSiteGenerator(chapters []*models.Chapter, outputDir string, tpl map[string]*template.Template, staticDir string) *SiteGenerator {
+func NewSiteGenerator(novels []*models.Novel, chapters []*models.Chapter, outputDir string, tpl map[string]*template.Template, manifest *assets.Manifest, urlBuilder *urls.Builder) *SiteGenerator {
 	return &SiteGenerator{
-		Chapters:    chapters,
-		OutputDir:   outputDir,
-		Templates:   tpl,
-		StaticDir:   staticDir,
+		Novels:    novels,
+		Chapters:  chapters,
+		OutputDir: outputDir,
+		Templates: tpl,
+		Assets:    manifest,
+		URLs:      urlBuilder,
 	}
 }
 
 // GenerateSite orchestrates the entire site generation process.
 func (sg *SiteGenerator) GenerateSite() error {
-	log.Println("Starting static site generation...")
+	start := time.Now()
+	if sg.Output == nil {
+		sg.Output = &DirOutput{Dir: sg.OutputDir}
+	}
+	slog.Info("starting static site generation", "output", sg.Output)
+
+	// 1. Prepare the output
+	if err := sg.Output.Prepare(); err != nil {
+		return fmt.Errorf("failed to prepare output: %w", err)
+	}
 
-	// 1. Prepare output directory
-	if err := sg.prepareOutputDir(); err != nil {
-		return fmt.Errorf("failed to prepare output directory: %w", err)
+	// 2. Organize chapters by novel and process them
+	novels, err := sg.Prepare()
+	if err != nil {
+		return err
 	}
 
-    // 2. Copy static assets (like CSS)
-    if err := sg.copyStaticAssets(); err != nil {
-		return fmt.Errorf("failed to copy static assets: %w", err)
+	for _, removal := range sg.removals {
+		slog.Warn("removed HTML not allowed by the sanitize policy", "novel", removal.Novel.Name, "volume", removal.Chapter.VolumeNumber, "chapter", removal.Chapter.ChapterNumber, "variant", removal.Variant.Name, "removed", removal.Report.String())
 	}
 
-	// 3. Organize chapters by novel and process them
-	novels := sg.organizeChapters()
+	// 3. Write the static assets (like CSS), purged of unused rules if asked
+	if sg.PurgeCSS {
+		if err := sg.purgeAssets(novels); err != nil {
+			return fmt.Errorf("failed to purge unused CSS: %w", err)
+		}
+	}
+	if err := sg.writeAssets(); err != nil {
+		return fmt.Errorf("failed to write static assets: %w", err)
+	}
 	if len(novels) == 0 {
-		log.Println("No novels found to generate.")
+		slog.Warn("no novels found to generate")
 		return nil
 	}
+	sg.checkNovelOverrides(novels)
 
 	// 4. Generate the main index page
 	if err := sg.generateIndexPage(novels); err != nil {
 		return fmt.Errorf("failed to generate index page: %w", err)
 	}
 
-	// 5. Generate pages for each chapter
-	if err := sg.generateChapterPages(novels); err != nil {
+	// 5. Generate the landing page and volume pages of each novel
+	written := sg.writtenNovels(novels)
+	if err := sg.generateNovelPages(written); err != nil {
+		return fmt.Errorf("failed to generate novel pages: %w", err)
+	}
+
+	// 6. Generate pages for each chapter
+	if err := sg.generateChapterPages(written); err != nil {
 		return fmt.Errorf("failed to generate chapter pages: %w", err)
 	}
 
-	log.Println("Static site generation completed successfully.")
-	return nil
-}
+	// 7. Generate the standalone content pages
+	if err := sg.generateContentPages(); err != nil {
+		return fmt.Errorf("failed to generate content pages: %w", err)
+	}
 
-// prepareOutputDir ensures the output directory exists and is clean.
-func (sg *SiteGenerator) prepareOutputDir() error {
-	// Optional: Remove existing directory for a clean build
-	// if err := os.RemoveAll(sg.OutputDir); err != nil {
-	//  log.Printf("Warning: Could not remove existing output directory '%s': %v", sg.OutputDir, err)
-	// }
+	// 8. Generate the character glossary
+	if err := sg.generateCharacterPages(); err != nil {
+		return fmt.Errorf("failed to generate character pages: %w", err)
+	}
 
-	// Create the base output directory
-	if err := os.MkdirAll(sg.OutputDir, 0755); err != nil {
-		return fmt.Errorf("could not create output directory '%s': %w", sg.OutputDir, err)
+	// 9. Generate the statistics page
+	if err := sg.generateStatsPage(novels); err != nil {
+		return fmt.Errorf("failed to generate statistics page: %w", err)
 	}
-	log.Printf("Output directory '%s' prepared.", sg.OutputDir)
-	return nil
-}
 
-// copyStaticAssets copies files from the static source directory to the output directory.
-func (sg *SiteGenerator) copyStaticAssets() error {
-	log.Printf("Copying static assets from '%s'...", sg.StaticDir)
-	return filepath.Walk(sg.StaticDir, func(srcPath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("error accessing path %q: %w", srcPath, err)
-		}
+	// 10. Generate the search index and page
+	if err := sg.generateSearch(novels); err != nil {
+		return fmt.Errorf("failed to generate search: %w", err)
+	}
 
-		// Calculate the destination path relative to the output directory
-		relPath, err := filepath.Rel(sg.StaticDir, srcPath)
-		if err != nil {
-			return fmt.Errorf("could not get relative path for %q: %w", srcPath, err)
+	// 11. Generate the sitemap and feeds (need an absolute base URL)
+	if sg.URLs.BaseURL == nil {
+		slog.Info("no base URL configured, skipping sitemap and feeds")
+	} else {
+		if err := sg.generateSitemap(novels); err != nil {
+			return fmt.Errorf("failed to generate sitemap: %w", err)
 		}
-		destPath := filepath.Join(sg.OutputDir, relPath)
-
-		if info.IsDir() {
-			// Create corresponding directory in output
-			if err := os.MkdirAll(destPath, info.Mode()); err != nil {
-				return fmt.Errorf("could not create directory %q: %w", destPath, err)
-			}
-			return nil // Don't copy the directory entry itself
+		if err := sg.generateFeeds(novels); err != nil {
+			return fmt.Errorf("failed to generate feeds: %w", err)
 		}
+	}
 
-		// Copy the file
-		log.Printf("Copying '%s' to '%s'", srcPath, destPath)
-		return copyFile(srcPath, destPath)
-	})
+	slog.Info("static site generation completed", "novels", len(novels), "chapters", len(sg.Chapters), "duration", time.Since(start))
+	return nil
 }
 
-// copyFile copies a single file from src to dst.
-func copyFile(src, dst string) error {
-    sourceFile, err := os.Open(src)
-    if err != nil {
-        return fmt.Errorf("could not open source file %q: %w", src, err)
-    }
-    defer sourceFile.Close()
-
-    destFile, err := os.Create(dst)
-    if err != nil {
-        return fmt.Errorf("could not create destination file %q: %w", dst, err)
-    }
-    defer destFile.Close()
-
-    _, err = io.Copy(destFile, sourceFile)
-    if err != nil {
-        return fmt.Errorf("could not copy data from %q to %q: %w", src, dst, err)
-    }
-
-    // Preserve file permissions (optional, but good practice)
-    info, err := os.Stat(src)
-    if err == nil {
-        err = os.Chmod(dst, info.Mode())
-        if err != nil {
-             log.Printf("Warning: Could not set permissions on %q: %v", dst, err)
-        }
-    } else {
-         log.Printf("Warning: Could not stat source file %q: %v", src, err)
-    }
-
-
-    return nil
+// Prepare organizes the content pages and chapters, assigning every output path
+// and the navigation links, and returns the novels sorted by name. GenerateSite
+// calls it; CheckTemplates uses it to set up fixtures without writing anything.
+func (sg *SiteGenerator) Prepare() ([]*models.Novel, error) {
+	if len(sg.Variants) == 0 {
+		sg.Variants = variants.Default()
+	}
+	if err := variants.Validate(sg.Variants); err != nil {
+		return nil, err
+	}
+	if sg.Formatter == nil {
+		// The glossary replaces the publisher's list of names when there is one
+		names := sg.characterNames()
+		if len(names) == 0 {
+			names = formatter.DefaultNames
+		}
+		sg.Formatter = formatter.New(names)
+	}
+	sg.claimed = map[string]string{sg.URLs.IndexPath(): "site index"}
+	if err := sg.organizePages(); err != nil {
+		return nil, fmt.Errorf("failed to organize content pages: %w", err)
+	}
+	novels, err := sg.organizeChapters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to organize chapters: %w", err)
+	}
+	sg.prepareContent(novels)
+	if err := sg.organizeStats(novels); err != nil {
+		return nil, fmt.Errorf("failed to organize statistics: %w", err)
+	}
+	if err := sg.organizeCharacters(novels); err != nil {
+		return nil, fmt.Errorf("failed to organize characters: %w", err)
+	}
+	if err := sg.organizeSearch(); err != nil {
+		return nil, fmt.Errorf("failed to organize search: %w", err)
+	}
+	return novels, nil
 }
 
+// writeAssets writes the processed theme static files to the output directory.
+func (sg *SiteGenerator) writeAssets() error {
+	start := time.Now()
+	list := sg.Assets.Assets()
+	for _, asset := range list {
+		if err := sg.claim(asset.Path, fmt.Sprintf("static asset '%s'", asset.Source)); err != nil {
+			return err
+		}
+		slog.Debug("writing static asset", "file", asset.Source, "output", asset.Path, "bytes", len(asset.Data))
+		if err := sg.writeFile(asset.Path, asset.Data); err != nil {
+			return err
+		}
+	}
+	slog.Info("wrote static assets", "files", len(list), "duration", time.Since(start))
+	return nil
+}
 
-// organizeChapters groups chapters by novel name and sets up navigation.
-func (sg *SiteGenerator) organizeChapters() []*models.Novel {
-	chaptersByNovel := make(map[string][]*models.Chapter)
-
-	// Group chapters by novel name
-	for _, ch := range sg.Chapters {
-		chaptersByNovel[ch.NovelName] = append(chaptersByNovel[ch.NovelName], ch)
+// organizeChapters attaches chapters to their novels and volumes and sets up navigation.
+// Chapters whose novel is missing from sg.Novels get a novel built from their NovelName.
+// It fails if the permalink pattern maps two pages to the same output path.
+func (sg *SiteGenerator) organizeChapters() ([]*models.Novel, error) {
+	novelsByID := make(map[int]*models.Novel, len(sg.Novels))
+	volumesByID := make(map[int]*models.Volume)
+	novels := make([]*models.Novel, 0, len(sg.Novels))
+	for _, novel := range sg.Novels {
+		novel.Chapters = nil
+		for _, volume := range novel.Volumes {
+			volume.Chapters = nil
+			volumesByID[volume.ID] = volume
+		}
+		novelsByID[novel.ID] = novel
+		novels = append(novels, novel)
 	}
 
-	novels := make([]*models.Novel, 0, len(chaptersByNovel))
+	// Group chapters by novel and volume
+	for _, ch := range sg.Chapters {
+		novel, ok := novelsByID[ch.NovelID]
+		if !ok {
+			slog.Warn("chapter references unknown novel, building it from the chapter row", "chapter_id", ch.ID, "novel_id", ch.NovelID)
+			novel = &models.Novel{ID: ch.NovelID, Name: ch.NovelName}
+			novelsByID[ch.NovelID] = novel
+			novels = append(novels, novel)
+		}
+		novel.Chapters = append(novel.Chapters, ch)
 
-	novelNames := make([]string, 0, len(chaptersByNovel))
-	for name := range chaptersByNovel {
-		novelNames = append(novelNames, name)
+		volume, ok := volumesByID[ch.VolumeID]
+		if !ok {
+			volume = &models.Volume{ID: ch.VolumeID, NovelID: novel.ID, Number: ch.VolumeNumber}
+			volumesByID[ch.VolumeID] = volume
+			novel.Volumes = append(novel.Volumes, volume)
+		}
+		volume.Chapters = append(volume.Chapters, ch)
 	}
-	sort.Strings(novelNames) // Sort novels alphabetically by name for consistent index
 
-	for _, novelName := range novelNames {
-		chapters := chaptersByNovel[novelName]
-		// Chapters should already be sorted by chapter_number from the DB query
-		// If not, sort here: sort.Slice(chapters, func(i, j int) bool { return chapters[i].ChapterNumber < chapters[j].ChapterNumber })
+	sort.SliceStable(novels, func(i, j int) bool { return novels[i].Name < novels[j].Name }) // Sort novels alphabetically by name for consistent index
+
+	claim := sg.claim
+	titleSlugsTaken := make(map[string]bool)
+	for _, novel := range novels {
+		// Chapters should already be sorted by volume and chapter number from the DB query
+		chapters := novel.Chapters
+		novel.Slug = utils.Slugify(novel.Name)
+		novel.Lang = sg.language(novel.Lang, "novel", novel.Name)
+		novel.Path = sg.URLs.NovelPath(novel)
+		novel.URL = sg.URLs.Link(novel.Path)
+		if err := claim(novel.Path, fmt.Sprintf("novel '%s'", novel.Name)); err != nil {
+			return nil, err
+		}
 
-		novelSlug := utils.Slugify(novelName)
-		novel := &models.Novel{
-			Name:     novelName,
-			Slug:     novelSlug,
-			Chapters: chapters,
+		sort.SliceStable(novel.Volumes, func(i, j int) bool { return novel.Volumes[i].Number < novel.Volumes[j].Number })
+		for _, volume := range novel.Volumes {
+			volume.Path = sg.URLs.VolumePath(novel, volume)
+			volume.URL = sg.URLs.Link(volume.Path)
+			if err := claim(volume.Path, fmt.Sprintf("volume %d of '%s'", volume.Number, novel.Name)); err != nil {
+				return nil, err
+			}
 		}
 
-		// Set filenames and Next/Prev links
+		// Set output paths and Next/Prev links
+		for _, ch := range chapters {
+			ch.NovelSlug = novel.Slug
+		}
+		sg.URLs.AssignTitleSlugs(chapters, titleSlugsTaken)
 		for i, ch := range chapters {
-			ch.NovelSlug = novelSlug
-			// Include Volume Number in the filename format
-			ch.FilenameHTML = fmt.Sprintf("v%d-c%d.html", ch.VolumeNumber, ch.ChapterNumber)
-			ch.FilenameBulma = fmt.Sprintf("v%d-c%d-styled.html", ch.VolumeNumber, ch.ChapterNumber)
+			ch.Paths = make(map[string]string, len(sg.Variants))
+			ch.URLs = make(map[string]string, len(sg.Variants))
+			for _, variant := range sg.Variants {
+				outputPath := sg.URLs.ChapterPath(ch, variant.Suffix)
+				ch.Paths[variant.Name] = outputPath
+				ch.URLs[variant.Name] = sg.URLs.Link(outputPath)
+				owner := fmt.Sprintf("chapter %d (V%d C%d of '%s', %s)", ch.ID, ch.VolumeNumber, ch.ChapterNumber, novel.Name, variant.Name)
+				if err := claim(outputPath, owner); err != nil {
+					return nil, err
+				}
+			}
+			ch.Path = ch.Paths[sg.Variants[0].Name]
+			ch.URL = ch.URLs[sg.Variants[0].Name]
 
 			if i > 0 {
 				ch.PrevChapter = chapters[i-1]
@@ -188,177 +297,294 @@ func (sg *SiteGenerator) organizeChapters() []*models.Novel {
 				ch.NextChapter = chapters[i+1]
 			}
 		}
-		novels = append(novels, novel)
 	}
 
-	return novels
+	return novels, nil
 }
 
-// generateIndexPage creates the main index.html file.
-func (sg *SiteGenerator) generateIndexPage(novels []*models.Novel) error {
-	indexPath := filepath.Join(sg.OutputDir, "index.html")
-	log.Printf("Generating index page: %s", indexPath)
+// organizePages assigns output paths to the content pages and builds the shared
+// site data, including the navigation menu.
+func (sg *SiteGenerator) organizePages() error {
+	sg.Lang = sg.language(sg.Lang, "site", "")
+	for _, page := range sg.Pages {
+		page.Lang = sg.language(page.Lang, "content page", page.Source)
+		page.Path = sg.URLs.PagePath(page)
+		page.URL = sg.URLs.Link(page.Path)
+		if err := sg.claim(page.Path, fmt.Sprintf("content page '%s'", page.Source)); err != nil {
+			return err
+		}
+	}
+	sg.site = &models.Site{
+		Title:    "Novel Site",
+		Lang:     sg.Lang,
+		Menu:     content.Menu(sg.Pages),
+		Variants: sg.Variants,
+	}
+	return nil
+}
 
-	data := models.IndexPageData{
-		Novels:        novels,
-		IsBulmaStyled: false,
-		SiteBasePath:  "",
+// language returns lang in canonical form, or the site language when lang is
+// empty or not a valid language tag (which is logged with the kind and name of
+// what set it).
+func (sg *SiteGenerator) language(lang, kind, name string) string {
+	fallback := sg.Lang
+	if fallback == "" {
+		fallback = i18n.DefaultLang
+	}
+	if lang == "" {
+		return fallback
 	}
+	normalized, ok := i18n.Normalize(lang)
+	if !ok {
+		slog.Warn("invalid language tag, using the site language", kind, name, "lang", lang, "fallback", fallback)
+		return fallback
+	}
+	return normalized
+}
 
-	// --- Execute template to buffer ---
-	var buf bytes.Buffer // Create an in-memory buffer
-	log.Printf("Attempting to execute template 'index.html' into buffer")
-	err := sg.Templates["index"].ExecuteTemplate(&buf, "_base.html", data) // Execute _base.html
-	if err != nil {
-		log.Printf("!!! ERROR executing template 'index.html' into buffer: %v", err)
-		// If executing to buffer fails, return the error immediately
-		return fmt.Errorf("could not execute index template to buffer: %w", err)
+// claim records that owner writes outputPath. Every output path must be unique,
+// otherwise pages silently overwrite each other.
+func (sg *SiteGenerator) claim(outputPath, owner string) error {
+	if prev, ok := sg.claimed[outputPath]; ok {
+		return fmt.Errorf("output path '%s' is used by both %s and %s; check the permalink pattern, novel names and content slugs", outputPath, prev, owner)
 	}
-	log.Printf("Successfully executed template 'index.html' into buffer")
+	sg.claimed[outputPath] = owner
+	return nil
+}
 
-	// --- Log buffer content (snippet) ---
-	outputHTML := buf.String()       // Get the string from the buffer
-	outputSnippet := outputHTML
-	if len(outputSnippet) > 200 {    // Limit log size
-		outputSnippet = outputSnippet[:200] + "..."
+// writtenNovels returns the novels whose own pages GenerateSite writes: those
+// in sg.OnlyNovels, or all of them. The pages listing every novel are written
+// either way, since a change to any novel can change them.
+func (sg *SiteGenerator) writtenNovels(novels []*models.Novel) []*models.Novel {
+	if sg.OnlyNovels == nil {
+		return novels
 	}
-	// Log the actual generated content snippet
-	log.Printf("  >>> Generated Index HTML (Snippet):\n---\n%s\n---", outputSnippet)
-	if len(outputHTML) < 150 {       // Check if it seems too short (basic HTML structure should be > 150 chars)
-		 log.Printf("  >>> WARNING: Generated Index HTML seems very short (length %d), base template likely missing?", len(outputHTML))
+	var written []*models.Novel
+	for _, novel := range novels {
+		if sg.OnlyNovels[novel.ID] {
+			written = append(written, novel)
+		}
 	}
+	slog.Info("writing the pages of changed novels only", "novels", len(written), "skipped", len(novels)-len(written))
+	return written
+}
 
-	// --- Write buffer to file ---
-	log.Printf("  Attempting os.WriteFile: %s", indexPath)
-	err = os.WriteFile(indexPath, buf.Bytes(), 0644) // Use WriteFile to write buffer content
-	if err != nil {
-		log.Printf("!!! ERROR writing buffer to file '%s': %v", indexPath, err)
-		return fmt.Errorf("could not write index file '%s': %w", indexPath, err)
+// generateIndexPage creates the main index.html file.
+func (sg *SiteGenerator) generateIndexPage(novels []*models.Novel) error {
+	indexPath := sg.URLs.IndexPath()
+	if err := sg.renderPage("index", indexPath, sg.Lang, sg.indexData(novels)); err != nil {
+		return err
 	}
-	log.Printf("  Successfully wrote buffer to file: %s", indexPath)
-
+	slog.Info("generated index page", "file", indexPath, "novels", len(novels))
 	return nil
 }
 
+// generateNovelPages creates <novel-slug>/index.html and <novel-slug>/v<N>/index.html
+// for every novel and volume.
+func (sg *SiteGenerator) generateNovelPages(novels []*models.Novel) error {
+	for _, novel := range novels {
+		if err := sg.renderPage(sg.pageTemplate("novel", novel), novel.Path, novel.Lang, sg.novelData(novel)); err != nil {
+			return err
+		}
 
-func (sg *SiteGenerator) generateChapterPages(novels []*models.Novel) error {
-	log.Println("--- Entering generateChapterPages ---") // Log entry into the function
-	for novelIndex, novel := range novels {
-		log.Printf("Processing Novel %d/%d: %s (Slug: %s)", novelIndex+1, len(novels), novel.Name, novel.Slug) // Log which novel
-		novelDir := filepath.Join(sg.OutputDir, novel.Slug)
-
-		// --- START DEBUG LOGGING ---
-		log.Printf("  Attempting to create novel directory: %s", novelDir)
-		// --- END DEBUG LOGGING ---
-		if err := os.MkdirAll(novelDir, 0755); err != nil {
-			log.Printf("!!! ERROR creating directory for novel '%s': %v", novel.Name, err) // Log error
-			return fmt.Errorf("could not create directory for novel '%s': %w", novel.Name, err)
+		for _, volume := range novel.Volumes {
+			if err := sg.renderPage(sg.pageTemplate("volume", novel), volume.Path, novel.Lang, sg.volumeData(novel, volume)); err != nil {
+				return err
+			}
 		}
-		log.Printf("  Successfully created/ensured novel directory: %s", novelDir) // Log success
+		slog.Info("generated novel pages", "novel", novel.Name, "slug", novel.Slug, "volumes", len(novel.Volumes))
+	}
+	return nil
+}
 
+// generateChapterPages renders every variant of every chapter.
+// A chapter that fails to render is logged and skipped so one bad row does not
+// abort the whole build.
+func (sg *SiteGenerator) generateChapterPages(novels []*models.Novel) error {
+	for _, novel := range novels {
+		start := time.Now()
+		logger := slog.With("novel", novel.Name)
 
-		log.Printf("  Generating %d chapters for novel: %s", len(novel.Chapters), novel.Name)
+		failed := 0
 		for chapterIndex, chapter := range novel.Chapters {
-			// --- START DEBUG LOGGING ---
-			// Check for nil chapter right away
 			if chapter == nil {
-				log.Printf("!!! ERROR: Chapter at index %d for novel '%s' is nil. Skipping.", chapterIndex, novel.Name)
+				logger.Error("nil chapter, skipping", "index", chapterIndex)
+				failed++
 				continue
 			}
-			log.Printf("  Processing Chapter %d/%d: Vol %d Ch %d (DB ID: %d)",
-				chapterIndex+1, len(novel.Chapters), chapter.VolumeNumber, chapter.ChapterNumber, chapter.ID) // Log which chapter
-			// --- END DEBUG LOGGING ---
-
-			// Generate Plain HTML version
-			err := sg.renderChapter(novelDir, chapter, false) // isBulmaStyled = false
-			if err != nil {
-				log.Printf("!!! Error generating plain HTML for chapter V%d C%d (%s): %v", chapter.VolumeNumber, chapter.ChapterNumber, novel.Name, err)
-				// Decide whether to continue or return error
-				// return err // Stop on first error
-				continue // Log and continue with next chapter
-			}
 
-			// Generate Bulma Styled HTML version
-			err = sg.renderChapter(novelDir, chapter, true) // isBulmaStyled = true
-			if err != nil {
-				log.Printf("!!! Error generating Bulma HTML for chapter V%d C%d (%s): %v", chapter.VolumeNumber, chapter.ChapterNumber, novel.Name, err)
-				// return err
-				continue
+			for _, variant := range sg.Variants {
+				if err := sg.renderChapter(novel, chapter, variant); err != nil {
+					logger.Error("failed to generate chapter", "variant", variant.Name,
+						"chapter_id", chapter.ID, "volume", chapter.VolumeNumber, "chapter", chapter.ChapterNumber, "error", err)
+					failed++
+					break
+				}
 			}
 		}
-		log.Printf("Finished generating chapters for novel: %s", novel.Name) // Log finish for novel
+		logger.Info("generated chapters", "slug", novel.Slug, "chapters", len(novel.Chapters), "failed", failed, "duration", time.Since(start))
 	}
-	log.Println("--- Exiting generateChapterPages ---") // Log exit from the function
 	return nil
 }
 
-// renderChapter writes a single chapter file (either plain or styled).
-func (sg *SiteGenerator) renderChapter(novelDir string, chapter *models.Chapter, isBulmaStyled bool) error {
-	log.Printf("    --- Entering renderChapter (DB ID: %d, Styled: %t) ---", chapter.ID, isBulmaStyled)
-
-	var filename string
-	styleType := "Plain HTML"
-	if isBulmaStyled {
-		log.Printf("      Inside 'if isBulmaStyled', about to access chapter.FilenameBulma (DB ID: %d)", chapter.ID)
-		filename = chapter.FilenameBulma
-		log.Printf("      Successfully accessed chapter.FilenameBulma. Value: '%s' (DB ID: %d)", filename, chapter.ID)
-		styleType = "Bulma Styled"
-	} else {
-		filename = chapter.FilenameHTML
+// renderChapter writes a single chapter file in one variant.
+func (sg *SiteGenerator) renderChapter(novel *models.Novel, chapter *models.Chapter, variant *models.Variant) error {
+	outputPath, data := sg.chapterData(novel, chapter, variant)
+	if outputPath == "" {
+		return fmt.Errorf("generated empty output path for chapter DB ID %d", chapter.ID)
 	}
+	return sg.renderPage(sg.pageTemplate(variant.Template, novel), outputPath, novel.Lang, data)
+}
 
-	if filename == "" {
-		log.Printf("!!! ERROR: Filename is empty after assignment for chapter DB ID %d (Styled: %t)", chapter.ID, isBulmaStyled)
-		return fmt.Errorf("generated empty filename for chapter DB ID %d", chapter.ID)
+// generateContentPages renders every content page with the layout named in its
+// front matter.
+func (sg *SiteGenerator) generateContentPages() error {
+	for _, page := range sg.Pages {
+		if err := sg.renderPage(page.Layout, page.Path, page.Lang, sg.contentData(page)); err != nil {
+			return fmt.Errorf("content page '%s': %w", page.Source, err)
+		}
+	}
+	if len(sg.Pages) > 0 {
+		slog.Info("generated content pages", "pages", len(sg.Pages))
 	}
-	log.Printf("    Determined filename: %s", filename)
+	return nil
+}
 
-	filePath := filepath.Join(novelDir, filename)
-	logPath := filepath.Join(chapter.NovelSlug, filename)
-	log.Printf("      Generating chapter file (%s): %s", styleType, logPath)
+// indexData builds the data for the site index.
+func (sg *SiteGenerator) indexData(novels []*models.Novel) models.IndexPageData {
+	return models.IndexPageData{
+		Site:         sg.site,
+		Novels:       novels,
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(sg.URLs.IndexPath()),
+		Lang:         sg.Lang,
+	}
+}
 
-	data := models.ChapterPageData{
-		NovelName:     chapter.NovelName,
-		NovelSlug:     chapter.NovelSlug,
-		Current:       chapter,
-		IsBulmaStyled: isBulmaStyled,
-		SiteBasePath:  "../",
+// novelData builds the data for a novel landing page.
+func (sg *SiteGenerator) novelData(novel *models.Novel) models.NovelPageData {
+	return models.NovelPageData{
+		Site:         sg.site,
+		Novel:        novel,
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(novel.Path),
+		Lang:         novel.Lang,
 	}
+}
 
-	// --- Execute template to buffer ---
-	var buf bytes.Buffer // Create an in-memory buffer
-	log.Printf("      Attempting to execute template 'chapter' into buffer for %s", logPath)
-	err := sg.Templates["chapter"].ExecuteTemplate(&buf, "_base.html", data) // Execute _base.html
-	if err != nil {
-		log.Printf("!!! ERROR executing template 'chapter' into buffer for '%s': %v", logPath, err)
-		// If executing to buffer fails, return the error immediately
-		return fmt.Errorf("could not execute chapter template to buffer for '%s': %w", logPath, err)
+// volumeData builds the data for a volume index page.
+func (sg *SiteGenerator) volumeData(novel *models.Novel, volume *models.Volume) models.VolumePageData {
+	return models.VolumePageData{
+		Site:         sg.site,
+		Novel:        novel,
+		Volume:       volume,
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(volume.Path),
+		Lang:         novel.Lang,
 	}
-	log.Printf("      Successfully executed template 'chapter.html' into buffer for %s", logPath)
+}
 
-	// --- Log buffer content (snippet) ---
-	outputHTML := buf.String()       // Get the string from the buffer
-	outputSnippet := outputHTML
-	if len(outputSnippet) > 200 {    // Limit log size
-		outputSnippet = outputSnippet[:200] + "..."
+// chapterData returns the output path and page data of one variant of a chapter.
+func (sg *SiteGenerator) chapterData(novel *models.Novel, chapter *models.Chapter, variant *models.Variant) (string, models.ChapterPageData) {
+	outputPath := chapter.Paths[variant.Name]
+	return outputPath, models.ChapterPageData{
+		Site:         sg.site,
+		NovelName:    novel.Name,
+		NovelSlug:    novel.Slug,
+		Novel:        novel,
+		Current:      chapter,
+		Content:      sg.linkCharacters(sg.chapterContent(chapter, variant), outputPath),
+		Variant:      variant,
+		SiteBasePath: sg.URLs.BasePath(outputPath),
+		Lang:         novel.Lang,
 	}
-	// Log the actual generated content snippet
-	log.Printf("        >>> Generated Chapter HTML (Snippet):\n---\n%s\n---", outputSnippet)
-	if len(outputHTML) < 150 {       // Check if it seems too short (basic HTML structure should be > 150 chars)
-		 log.Printf("        >>> WARNING: Generated Chapter HTML seems very short (length %d), base template likely missing?", len(outputHTML))
+}
+
+// contentData builds the data for a standalone content page.
+func (sg *SiteGenerator) contentData(page *models.Page) models.ContentPageData {
+	return models.ContentPageData{
+		Site:         sg.site,
+		Page:         page,
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(page.Path),
+		Lang:         page.Lang,
 	}
+}
 
+// pageTemplate returns the template to render page (e.g. "chapter") with for
+// novel: its per-novel override when the theme has one, page otherwise.
+func (sg *SiteGenerator) pageTemplate(page string, novel *models.Novel) string {
+	if novel != nil {
+		if name := theme.NovelOverride(novel.Slug, page); sg.Templates[name] != nil {
+			return name
+		}
+	}
+	return page
+}
 
-	// --- Write buffer to file ---
-	log.Printf("        Attempting os.WriteFile: %s", filePath)
-	err = os.WriteFile(filePath, buf.Bytes(), 0644) // Use WriteFile to write buffer content
-	if err != nil {
-		log.Printf("!!! ERROR writing buffer to file '%s': %v", filePath, err)
-		return fmt.Errorf("could not write chapter file '%s': %w", logPath, err)
+// checkNovelOverrides logs which novels use override templates and warns about
+// overrides whose slug matches no novel, which is usually a typo.
+func (sg *SiteGenerator) checkNovelOverrides(novels []*models.Novel) {
+	slugs := make(map[string]bool, len(novels))
+	for _, novel := range novels {
+		slugs[novel.Slug] = true
+	}
+	for name := range sg.Templates {
+		slug, ok := theme.OverrideSlug(name)
+		if !ok {
+			continue
+		}
+		if !slugs[slug] {
+			slog.Warn("template override matches no novel", "template", name, "slug", slug)
+			continue
+		}
+		slog.Info("using per-novel template override", "template", name, "slug", slug)
 	}
-	log.Printf("        Successfully wrote buffer to file: %s", filePath)
-	log.Printf("    --- Exiting renderChapter (DB ID: %d, Styled: %t) ---", chapter.ID, isBulmaStyled)
+}
 
+// renderPage renders the named page template for relPath in lang and writes the
+// result to relPath (slash-separated) inside the output directory.
+func (sg *SiteGenerator) renderPage(name, relPath, lang string, data any) error {
+	start := time.Now()
+	out, err := sg.render(name, relPath, lang, data)
+	if err != nil {
+		return err
+	}
+	// Basic HTML structure should be longer than this; a tiny page usually means
+	// the page template never hooked into _base.html.
+	if len(out) < minPageSize {
+		slog.Warn("generated page seems very short, base template likely missing?", "template", name, "file", relPath, "bytes", len(out))
+	}
 
+	if err := sg.writeFile(relPath, out); err != nil {
+		return err
+	}
+	slog.Debug("rendered page", "template", name, "file", relPath, "bytes", len(out), "duration", time.Since(start))
 	return nil
 }
+
+// minPageSize is the size below which a rendered page is suspiciously short.
+const minPageSize = 150
+
+// render executes the named page template through _base.html for the page
+// written at relPath in lang. The template functions are rebound to relPath and
+// lang first, so pages must be rendered one at a time.
+func (sg *SiteGenerator) render(name, relPath, lang string, data any) ([]byte, error) {
+	tmpl, ok := sg.Templates[name]
+	if !ok {
+		return nil, fmt.Errorf("template %q not loaded", name)
+	}
+
+	tmpl.Funcs(templatefuncs.New(sg.URLs, sg.Catalog, sg.Assets, relPath, lang))
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "_base.html", data); err != nil {
+		return nil, fmt.Errorf("could not execute %s template for '%s': %w", name, relPath, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFile writes data to relPath in the output.
+func (sg *SiteGenerator) writeFile(relPath string, data []byte) error {
+	return sg.Output.WriteFile(relPath, data)
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Output receives the files of the generated site.
+type Output interface {
+	// Prepare readies the output for a new build.
+	Prepare() error
+	// WriteFile stores data at relPath, a slash-separated path relative to the
+	// site root.
+	WriteFile(relPath string, data []byte) error
+}
+
+// DirOutput writes the site into a directory. Files of earlier builds that the
+// new one does not write are left in place.
+type DirOutput struct {
+	Dir string
+}
+
+// Prepare creates the directory.
+func (o *DirOutput) Prepare() error {
+	if err := os.MkdirAll(o.Dir, 0755); err != nil {
+		return fmt.Errorf("could not create output directory '%s': %w", o.Dir, err)
+	}
+	slog.Debug("output directory prepared", "dir", o.Dir)
+	return nil
+}
+
+// WriteFile writes data to relPath inside the directory, creating parent
+// directories as needed.
+func (o *DirOutput) WriteFile(relPath string, data []byte) error {
+	filePath := filepath.Join(o.Dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("could not create directory for '%s': %w", relPath, err)
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("could not write file '%s': %w", relPath, err)
+	}
+	return nil
+}
+
+func (o *DirOutput) String() string {
+	return o.Dir
+}
+
+// MemoryOutput keeps the site in memory, for the preview server. It is not safe
+// for concurrent use while a build writes to it; the server builds into a new
+// one and swaps it in when the build is done.
+type MemoryOutput struct {
+	files map[string][]byte
+}
+
+// NewMemoryOutput returns an empty MemoryOutput.
+func NewMemoryOutput() *MemoryOutput {
+	return &MemoryOutput{files: make(map[string][]byte)}
+}
+
+// Prepare forgets the files of any earlier build.
+func (o *MemoryOutput) Prepare() error {
+	o.files = make(map[string][]byte)
+	return nil
+}
+
+// WriteFile stores data at relPath.
+func (o *MemoryOutput) WriteFile(relPath string, data []byte) error {
+	o.files[relPath] = data
+	return nil
+}
+
+// File returns the file written at relPath.
+func (o *MemoryOutput) File(relPath string) ([]byte, bool) {
+	data, ok := o.files[relPath]
+	return data, ok
+}
+
+// Paths lists the files written, sorted.
+func (o *MemoryOutput) Paths() []string {
+	paths := make([]string, 0, len(o.files))
+	for relPath := range o.files {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (o *MemoryOutput) String() string {
+	return "memory"
+}
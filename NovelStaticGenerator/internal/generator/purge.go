@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/assets"
+	"NovelStaticGenerator/internal/models"
+	"log/slog"
+	"time"
+)
+
+// purgeAssets renders every page without writing it, collects the class names
+// the pages use and replaces sg.Assets with stylesheets purged of the rules no
+// page can match. The pages are rendered again for real afterwards, now
+// linking to the purged (and re-fingerprinted) files.
+func (sg *SiteGenerator) purgeAssets(novels []*models.Novel) error {
+	start := time.Now()
+	used := make(map[string]bool)
+	pages := 0
+	sg.eachPage(novels, func(name, relPath, lang string, data any) {
+		out, err := sg.render(name, relPath, lang, data)
+		if err != nil {
+			// The real rendering pass reports it
+			slog.Debug("page failed while collecting CSS classes", "template", name, "file", relPath, "error", err)
+			return
+		}
+		assets.CollectClasses(out, used)
+		pages++
+	})
+	slog.Debug("collected CSS classes", "pages", pages, "classes", len(used), "duration", time.Since(start))
+
+	purged, err := sg.Assets.Purged(assets.KeepClasses(used, sg.PurgeSafelist))
+	if err != nil {
+		return err
+	}
+	sg.Assets = purged
+	return nil
+}
+
+// eachPage calls fn with the template, output path, language and data of every
+// HTML page of the site, in the order GenerateSite writes them.
+func (sg *SiteGenerator) eachPage(novels []*models.Novel, fn func(name, relPath, lang string, data any)) {
+	fn("index", sg.URLs.IndexPath(), sg.Lang, sg.indexData(novels))
+	for _, novel := range novels {
+		fn(sg.pageTemplate("novel", novel), novel.Path, novel.Lang, sg.novelData(novel))
+		for _, volume := range novel.Volumes {
+			fn(sg.pageTemplate("volume", novel), volume.Path, novel.Lang, sg.volumeData(novel, volume))
+		}
+	}
+	for _, novel := range novels {
+		for _, chapter := range novel.Chapters {
+			if chapter == nil {
+				continue
+			}
+			for _, variant := range sg.Variants {
+				relPath, data := sg.chapterData(novel, chapter, variant)
+				fn(sg.pageTemplate(variant.Template, novel), relPath, novel.Lang, data)
+			}
+		}
+	}
+	for _, page := range sg.Pages {
+		fn(page.Layout, page.Path, page.Lang, sg.contentData(page))
+	}
+	if len(sg.site.Characters) > 0 {
+		fn("characters", sg.URLs.CharactersPath(), sg.Lang, sg.charactersData())
+		for _, character := range sg.site.Characters {
+			fn("character", character.Path, sg.Lang, sg.characterData(character))
+		}
+	}
+	fn("stats", sg.URLs.StatsPath(), sg.Lang, sg.statsData(novels))
+	if sg.Search {
+		fn("search", sg.URLs.SearchPath(), sg.Lang, sg.searchData())
+	}
+}
@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/search"
+	"NovelStaticGenerator/internal/templatefuncs"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// organizeSearch claims the search page and index manifest when search is on.
+// The shards are claimed as they are written, since their names depend on the
+// words of the chapters.
+func (sg *SiteGenerator) organizeSearch() error {
+	if !sg.Search {
+		return nil
+	}
+	if err := sg.claim(sg.URLs.SearchPath(), "search page"); err != nil {
+		return err
+	}
+	if err := sg.claim(sg.URLs.SearchIndexPath("index.json"), "search index"); err != nil {
+		return err
+	}
+	sg.site.SearchURL = sg.URLs.Link(sg.URLs.SearchPath())
+	return nil
+}
+
+// generateSearch indexes the text of every chapter in its primary variant and
+// writes the index files and the search page.
+func (sg *SiteGenerator) generateSearch(novels []*models.Novel) error {
+	if !sg.Search {
+		return nil
+	}
+	start := time.Now()
+	p := sg.Catalog.Printer(sg.Lang)
+	index := search.New()
+	for _, novel := range novels {
+		for _, chapter := range novel.Chapters {
+			if chapter == nil {
+				continue
+			}
+			title := p.Sprintf("Vol. %d Ch. %d", chapter.VolumeNumber, chapter.ChapterNumber)
+			if novel.Name != "" {
+				title = novel.Name + " – " + title
+			}
+			if chapter.Title != "" {
+				title += ": " + chapter.Title
+			}
+			text := strings.Join(strings.Fields(templatefuncs.PlainText(sg.chapterContent(chapter, sg.Variants[0]))), " ")
+			index.Add(search.Document{
+				URL:     chapter.URL,
+				Title:   title,
+				Excerpt: templatefuncs.Truncate(160, text),
+				Text:    title + "\n" + text,
+			})
+		}
+	}
+
+	files, err := index.Files()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		relPath := sg.URLs.SearchIndexPath(name)
+		if name != "index.json" {
+			if err := sg.claim(relPath, fmt.Sprintf("search index shard '%s'", name)); err != nil {
+				return err
+			}
+		}
+		if err := sg.writeFile(relPath, files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := sg.renderPage("search", sg.URLs.SearchPath(), sg.Lang, sg.searchData()); err != nil {
+		return err
+	}
+	slog.Info("generated search index", "terms", index.Terms(), "files", len(files), "duration", time.Since(start))
+	return nil
+}
+
+// searchData builds the data for the search page.
+func (sg *SiteGenerator) searchData() models.SearchPageData {
+	return models.SearchPageData{
+		Site:         sg.site,
+		IndexURL:     sg.URLs.SearchIndexPath("index.json"),
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(sg.URLs.SearchPath()),
+		Lang:         sg.Lang,
+	}
+}
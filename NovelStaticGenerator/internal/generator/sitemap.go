@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// sitemapURLSet is the root element of sitemap.xml (sitemaps.org protocol 0.9).
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// generateSitemap writes sitemap.xml listing the index, the content pages, every
+// novel and volume page and the primary variant of every chapter. The other variants carry
+// the same text, so they are left out to avoid duplicate entries.
+func (sg *SiteGenerator) generateSitemap(novels []*models.Novel) error {
+	sitemapPath := "sitemap.xml"
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	add := func(pagePath string, lastMod time.Time) {
+		entry := sitemapURL{Loc: sg.URLs.CanonicalURL(pagePath)}
+		if !lastMod.IsZero() {
+			entry.LastMod = lastMod.UTC().Format("2006-01-02")
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+
+	add(sg.URLs.IndexPath(), time.Time{})
+	for _, page := range sg.Pages {
+		add(page.Path, time.Time{})
+	}
+	for _, novel := range novels {
+		add(novel.Path, latestUpdate(novel.Chapters))
+		for _, volume := range novel.Volumes {
+			add(volume.Path, latestUpdate(volume.Chapters))
+		}
+		for _, chapter := range novel.Chapters {
+			add(chapter.Path, chapter.UpdatedAt)
+		}
+	}
+
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode sitemap: %w", err)
+	}
+	if err := sg.writeFile(sitemapPath, append([]byte(xml.Header), data...)); err != nil {
+		return err
+	}
+	slog.Info("generated sitemap", "file", sitemapPath, "urls", len(set.URLs))
+	return nil
+}
+
+// latestUpdate returns the most recent UpdatedAt among chapters (zero if none).
+func latestUpdate(chapters []*models.Chapter) time.Time {
+	var latest time.Time
+	for _, ch := range chapters {
+		if ch.UpdatedAt.After(latest) {
+			latest = ch.UpdatedAt
+		}
+	}
+	return latest
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/stats"
+	"log/slog"
+)
+
+// organizeStats measures every chapter in its primary variant, adds the
+// lengths up per volume and novel, and claims the statistics page. It runs
+// after prepareContent.
+func (sg *SiteGenerator) organizeStats(novels []*models.Novel) error {
+	for _, novel := range novels {
+		for _, chapter := range novel.Chapters {
+			if chapter != nil {
+				chapter.Stats = stats.Count(sg.chapterContent(chapter, sg.Variants[0]))
+			}
+		}
+		for _, volume := range novel.Volumes {
+			volume.Stats = stats.Sum(volume.Chapters)
+		}
+		novel.Stats = stats.Sum(novel.Chapters)
+	}
+	if err := sg.claim(sg.URLs.StatsPath(), "statistics page"); err != nil {
+		return err
+	}
+	sg.site.StatsURL = sg.URLs.Link(sg.URLs.StatsPath())
+	return nil
+}
+
+// generateStatsPage writes the statistics page.
+func (sg *SiteGenerator) generateStatsPage(novels []*models.Novel) error {
+	data := sg.statsData(novels)
+	if err := sg.renderPage("stats", sg.URLs.StatsPath(), sg.Lang, data); err != nil {
+		return err
+	}
+	slog.Info("generated statistics page", "file", sg.URLs.StatsPath(), "chapters", data.Totals.Chapters, "words", data.Totals.Words)
+	return nil
+}
+
+// statsData builds the data for the statistics page, drawing the charts of
+// every novel with their labels in the site language.
+func (sg *SiteGenerator) statsData(novels []*models.Novel) models.StatsPageData {
+	p := sg.Catalog.Printer(sg.Lang)
+	data := models.StatsPageData{
+		Site:         sg.site,
+		Variant:      sg.Variants[0],
+		SiteBasePath: sg.URLs.BasePath(sg.URLs.StatsPath()),
+		Lang:         sg.Lang,
+	}
+	for _, novel := range novels {
+		data.Totals = stats.Add(data.Totals, novel.Stats)
+
+		lengths := stats.Chart{Title: p.Sprintf("Words per chapter of %s", novel.Name)}
+		group, volume := -1, -1
+		longest := 0
+		for _, chapter := range novel.Chapters {
+			if chapter == nil {
+				continue
+			}
+			if chapter.VolumeNumber != volume {
+				group, volume = group+1, chapter.VolumeNumber
+			}
+			position := p.Sprintf("Vol. %d Ch. %d", chapter.VolumeNumber, chapter.ChapterNumber)
+			if lengths.StartLabel == "" {
+				lengths.StartLabel = position
+			}
+			lengths.EndLabel = position
+			lengths.Bars = append(lengths.Bars, stats.Bar{Value: chapter.Stats.Words, Label: position + ": " + p.Sprintf("%d words", chapter.Stats.Words), Group: group})
+			longest = max(longest, chapter.Stats.Words)
+		}
+		lengths.MaxLabel = p.Sprintf("%d words", longest)
+
+		cadence := stats.Chart{Title: p.Sprintf("Chapters per month of %s", novel.Name)}
+		busiest := 0
+		months := stats.Cadence(novel.Chapters)
+		for i, month := range months {
+			label := month.Start.Format("2006-01")
+			if i == 0 {
+				cadence.StartLabel = label
+			}
+			cadence.EndLabel = label
+			cadence.Bars = append(cadence.Bars, stats.Bar{Value: month.Chapters, Label: label + ": " + p.Sprintf("%d chapters", month.Chapters), Group: month.Start.Year()})
+			busiest = max(busiest, month.Chapters)
+		}
+		cadence.MaxLabel = p.Sprintf("%d chapters", busiest)
+
+		data.Novels = append(data.Novels, &models.NovelStats{Novel: novel, LengthChart: lengths.SVG(), CadenceChart: cadence.SVG()})
+	}
+	return data
+}
@@ -0,0 +1,307 @@
+// Package htmltoken splits HTML fragments such as chapter content into tags and
+// text. It is a small subset of the HTML5 tokenizer, enough for the passes that
+// rewrite chapters (sanitizing, anchors, typography) without an external
+// dependency. It is lenient the way browsers are: a '<' that starts no tag is
+// text, unterminated comments and tags run to the end of the input, and the
+// content of raw text elements such as <script> is never parsed for tags.
+package htmltoken
+
+import (
+	"html"
+	"strings"
+)
+
+// Type is the kind of a Token.
+type Type int
+
+const (
+	Text    Type = iota
+	RawText      // Content of a raw text element such as <script> or <style>
+	StartTag
+	EndTag
+	Comment // Also doctypes, <? … > and other markup declarations
+)
+
+// Attribute is one attribute of a start tag.
+type Attribute struct {
+	Name     string // Lower-cased
+	Value    string // Raw (still entity-encoded) value
+	HasValue bool
+}
+
+// Token is one piece of the input.
+type Token struct {
+	Type        Type
+	Data        string // Text, comment source or lower-cased tag name
+	Attrs       []Attribute
+	SelfClosing bool
+	Raw         string // The token exactly as it appears in the input
+}
+
+// Attr returns the decoded value of the named attribute.
+func (t Token) Attr(name string) (string, bool) {
+	for _, attr := range t.Attrs {
+		if attr.Name == name {
+			return html.UnescapeString(attr.Value), true
+		}
+	}
+	return "", false
+}
+
+// SetAttr replaces the value of the named attribute, or adds the attribute.
+// value is not encoded yet.
+func (t *Token) SetAttr(name, value string) {
+	attr := Attribute{Name: name, Value: html.EscapeString(value), HasValue: true}
+	for i := range t.Attrs {
+		if t.Attrs[i].Name == name {
+			t.Attrs[i] = attr
+			return
+		}
+	}
+	t.Attrs = append(t.Attrs, attr)
+}
+
+// TagString writes a start or end tag in normal form: lower-case names and
+// double-quoted, re-encoded attribute values.
+func (t Token) TagString() string {
+	if t.Type == EndTag {
+		return "</" + t.Data + ">"
+	}
+	var b strings.Builder
+	b.WriteString("<" + t.Data)
+	for _, attr := range t.Attrs {
+		b.WriteString(" " + attr.Name)
+		if attr.HasValue {
+			b.WriteString(`="` + html.EscapeString(html.UnescapeString(attr.Value)) + `"`)
+		}
+	}
+	switch {
+	case IsVoid(t.Data):
+		b.WriteString(" />")
+	case t.SelfClosing:
+		b.WriteString("></" + t.Data + ">")
+	default:
+		b.WriteString(">")
+	}
+	return b.String()
+}
+
+// rawTextElements hold text that is not parsed for markup up to their end tag.
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+	"xmp": true, "iframe": true, "noembed": true, "noframes": true, "plaintext": true,
+}
+
+// voidElements have no content and no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// IsVoid reports whether the element has no content and no end tag, like <br>.
+func IsVoid(tag string) bool {
+	return voidElements[tag]
+}
+
+// Tokenizer reads the tokens of an HTML fragment.
+type Tokenizer struct {
+	src     string
+	pos     int
+	rawText string // Name of the raw text element being read, "" otherwise
+}
+
+// New returns a Tokenizer reading src.
+func New(src string) *Tokenizer {
+	return &Tokenizer{src: src}
+}
+
+// Next returns the next token, or false at the end of the input.
+func (z *Tokenizer) Next() (Token, bool) {
+	if z.pos >= len(z.src) {
+		return Token{}, false
+	}
+	start := z.pos
+	tok := z.next()
+	tok.Raw = z.src[start:z.pos]
+	return tok, true
+}
+
+func (z *Tokenizer) next() Token {
+	if z.rawText != "" {
+		return z.readRawText()
+	}
+
+	rest := z.src[z.pos:]
+	if rest[0] != '<' {
+		end := strings.IndexByte(rest, '<')
+		if end < 0 {
+			end = len(rest)
+		}
+		z.pos += end
+		return Token{Type: Text, Data: rest[:end]}
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		end := strings.Index(rest[4:], "-->")
+		if end < 0 {
+			z.pos = len(z.src)
+			return Token{Type: Comment, Data: rest}
+		}
+		z.pos += 4 + end + 3
+		return Token{Type: Comment, Data: rest[:4+end+3]}
+	case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+		return z.readUntilClose(Comment)
+	case strings.HasPrefix(rest, "</") && len(rest) > 2 && isASCIILetter(rest[2]):
+		z.pos += 2
+		tok := Token{Type: EndTag, Data: z.readTagName()}
+		z.skipPast('>')
+		return tok
+	case len(rest) > 1 && isASCIILetter(rest[1]):
+		z.pos++
+		return z.readStartTag()
+	case strings.HasPrefix(rest, "</"):
+		// "</>" and "</ 3" are dropped like a comment
+		return z.readUntilClose(Comment)
+	}
+	z.pos++
+	return Token{Type: Text, Data: "<"}
+}
+
+// readRawText reads the content of a raw text element up to its end tag.
+func (z *Tokenizer) readRawText() Token {
+	rest := z.src[z.pos:]
+	closing := "</" + z.rawText
+	end := 0
+	for {
+		i := indexFold(rest[end:], closing)
+		if i < 0 {
+			end = len(rest)
+			break
+		}
+		end += i
+		after := end + len(closing)
+		if after >= len(rest) || isSpace(rest[after]) || rest[after] == '>' || rest[after] == '/' {
+			break
+		}
+		end = after
+	}
+	z.pos += end
+	z.rawText = ""
+	return Token{Type: RawText, Data: rest[:end]}
+}
+
+// readStartTag reads a start tag whose name begins at z.pos.
+func (z *Tokenizer) readStartTag() Token {
+	tok := Token{Type: StartTag, Data: z.readTagName()}
+	for z.pos < len(z.src) {
+		c := z.src[z.pos]
+		switch {
+		case isSpace(c):
+			z.pos++
+		case c == '>':
+			z.pos++
+			if rawTextElements[tok.Data] {
+				z.rawText = tok.Data
+			}
+			return tok
+		case c == '/':
+			z.pos++
+			if z.pos < len(z.src) && z.src[z.pos] == '>' {
+				z.pos++
+				tok.SelfClosing = true
+				return tok
+			}
+		default:
+			tok.Attrs = append(tok.Attrs, z.readAttribute())
+		}
+	}
+	return tok
+}
+
+// readAttribute reads one attribute starting at z.pos.
+func (z *Tokenizer) readAttribute() Attribute {
+	start := z.pos
+	// The first character is part of the name even if it is '='
+	z.pos++
+	for z.pos < len(z.src) && !isSpace(z.src[z.pos]) && !strings.ContainsRune("/>=", rune(z.src[z.pos])) {
+		z.pos++
+	}
+	attr := Attribute{Name: strings.ToLower(z.src[start:z.pos])}
+
+	i := z.pos
+	for i < len(z.src) && isSpace(z.src[i]) {
+		i++
+	}
+	if i >= len(z.src) || z.src[i] != '=' {
+		return attr
+	}
+	z.pos = i + 1
+	for z.pos < len(z.src) && isSpace(z.src[z.pos]) {
+		z.pos++
+	}
+	attr.HasValue = true
+	if z.pos >= len(z.src) {
+		return attr
+	}
+	if quote := z.src[z.pos]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(z.src[z.pos+1:], quote)
+		if end < 0 {
+			attr.Value = z.src[z.pos+1:]
+			z.pos = len(z.src)
+			return attr
+		}
+		attr.Value = z.src[z.pos+1 : z.pos+1+end]
+		z.pos += 1 + end + 1
+		return attr
+	}
+	start = z.pos
+	for z.pos < len(z.src) && !isSpace(z.src[z.pos]) && z.src[z.pos] != '>' {
+		z.pos++
+	}
+	attr.Value = z.src[start:z.pos]
+	return attr
+}
+
+// readTagName reads a tag name starting at z.pos and lower-cases it.
+func (z *Tokenizer) readTagName() string {
+	start := z.pos
+	for z.pos < len(z.src) && !isSpace(z.src[z.pos]) && z.src[z.pos] != '/' && z.src[z.pos] != '>' {
+		z.pos++
+	}
+	return strings.ToLower(z.src[start:z.pos])
+}
+
+// readUntilClose returns everything up to and including the next '>'.
+func (z *Tokenizer) readUntilClose(typ Type) Token {
+	start := z.pos
+	z.skipPast('>')
+	return Token{Type: typ, Data: z.src[start:z.pos]}
+}
+
+// skipPast moves z.pos just past the next c, or to the end of the input.
+func (z *Tokenizer) skipPast(c byte) {
+	if end := strings.IndexByte(z.src[z.pos:], c); end >= 0 {
+		z.pos += end + 1
+	} else {
+		z.pos = len(z.src)
+	}
+}
+
+// indexFold is strings.Index ignoring ASCII case.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
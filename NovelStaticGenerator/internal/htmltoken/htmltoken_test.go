@@ -0,0 +1,159 @@
+package htmltoken
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tokens returns every token of src.
+func tokens(src string) []Token {
+	var all []Token
+	z := New(src)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			return all
+		}
+		all = append(all, tok)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []Token
+	}{
+		{
+			name: "tags and text",
+			in:   `<P Class="a">x<br/></p>`,
+			want: []Token{
+				{Type: StartTag, Data: "p", Attrs: []Attribute{{Name: "class", Value: "a", HasValue: true}}, Raw: `<P Class="a">`},
+				{Type: Text, Data: "x", Raw: "x"},
+				{Type: StartTag, Data: "br", SelfClosing: true, Raw: "<br/>"},
+				{Type: EndTag, Data: "p", Raw: "</p>"},
+			},
+		},
+		{
+			name: "attribute forms",
+			in:   `<a b c=1 d = '2' e="&amp;">`,
+			want: []Token{{Type: StartTag, Data: "a", Attrs: []Attribute{
+				{Name: "b"},
+				{Name: "c", Value: "1", HasValue: true},
+				{Name: "d", Value: "2", HasValue: true},
+				{Name: "e", Value: "&amp;", HasValue: true},
+			}, Raw: `<a b c=1 d = '2' e="&amp;">`}},
+		},
+		{
+			name: "script content is raw text",
+			in:   `<script>if (a < b) { x("</p>") }</script>`,
+			want: []Token{
+				{Type: StartTag, Data: "script", Raw: "<script>"},
+				{Type: RawText, Data: `if (a < b) { x("</p>") }`, Raw: `if (a < b) { x("</p>") }`},
+				{Type: EndTag, Data: "script", Raw: "</script>"},
+			},
+		},
+		{
+			name: "raw text ends only at its own end tag",
+			in:   `<style></styles></STYLE >`,
+			want: []Token{
+				{Type: StartTag, Data: "style", Raw: "<style>"},
+				{Type: RawText, Data: "</styles>", Raw: "</styles>"},
+				{Type: EndTag, Data: "style", Raw: "</STYLE >"},
+			},
+		},
+		{
+			name: "unclosed raw text runs to the end",
+			in:   `<script>a<p>`,
+			want: []Token{
+				{Type: StartTag, Data: "script", Raw: "<script>"},
+				{Type: RawText, Data: "a<p>", Raw: "a<p>"},
+			},
+		},
+		{
+			name: "comments and declarations",
+			in:   `<!-- a --><!doctype html><?xml x?></ 3>`,
+			want: []Token{
+				{Type: Comment, Data: "<!-- a -->", Raw: "<!-- a -->"},
+				{Type: Comment, Data: "<!doctype html>", Raw: "<!doctype html>"},
+				{Type: Comment, Data: "<?xml x?>", Raw: "<?xml x?>"},
+				{Type: Comment, Data: "</ 3>", Raw: "</ 3>"},
+			},
+		},
+		{
+			name: "unterminated comment runs to the end",
+			in:   `a<!-- b <p>`,
+			want: []Token{
+				{Type: Text, Data: "a", Raw: "a"},
+				{Type: Comment, Data: "<!-- b <p>", Raw: "<!-- b <p>"},
+			},
+		},
+		{
+			name: "a '<' starting no tag is text",
+			in:   `1 <2 <`,
+			want: []Token{
+				{Type: Text, Data: "1 ", Raw: "1 "},
+				{Type: Text, Data: "<", Raw: "<"},
+				{Type: Text, Data: "2 ", Raw: "2 "},
+				{Type: Text, Data: "<", Raw: "<"},
+			},
+		},
+		{
+			name: "unterminated tag runs to the end",
+			in:   `<a href="x`,
+			want: []Token{{Type: StartTag, Data: "a", Attrs: []Attribute{{Name: "href", Value: "x", HasValue: true}}, Raw: `<a href="x`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokens(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens(%q)\n got %+v\nwant %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawRoundTrip(t *testing.T) {
+	src := `<p a=1>x < y <!-- c --> <script>"</p>"</script><br/> </em`
+	var b strings.Builder
+	for _, tok := range tokens(src) {
+		b.WriteString(tok.Raw)
+	}
+	if b.String() != src {
+		t.Errorf("joined Raw = %q, want %q", b.String(), src)
+	}
+}
+
+func TestTagString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`<P CLASS=a hidden>`, `<p class="a" hidden>`},
+		{`<img src='a"b.png' alt="&lt;x&gt;">`, `<img src="a&#34;b.png" alt="&lt;x&gt;" />`},
+		{`<br>`, `<br />`},
+		{`<span/>`, `<span></span>`},
+		{`</DIV >`, `</div>`},
+	}
+	for _, tt := range tests {
+		if got := tokens(tt.in)[0].TagString(); got != tt.want {
+			t.Errorf("TagString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAttrs(t *testing.T) {
+	tok := tokens(`<a href="a&amp;b" id=x>`)[0]
+	if v, ok := tok.Attr("href"); !ok || v != "a&b" {
+		t.Errorf(`Attr("href") = %q, %v; want "a&b", true`, v, ok)
+	}
+	if _, ok := tok.Attr("title"); ok {
+		t.Error(`Attr("title") found an attribute that is not there`)
+	}
+	tok.SetAttr("id", `"y"`)
+	tok.SetAttr("title", "a<b")
+	if got, want := tok.TagString(), `<a href="a&amp;b" id="&#34;y&#34;" title="a&lt;b">`; got != want {
+		t.Errorf("after SetAttr, TagString() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// Dir is the theme directory holding the message catalogs, one <lang>.json file
+// per language (e.g. i18n/es.json).
+const Dir = "i18n"
+
+// DefaultLang is the site language when none is configured.
+const DefaultLang = "en"
+
+// Message keys are the English text as a fmt format string, so a key missing
+// from a catalog renders as English:
+//
+//	{
+//	  "Table of Contents": "Índice",
+//	  "%d chapters": {"one": "%d capítulo", "other": "%d capítulos"}
+//	}
+//
+// An object value selects a form by the plural category of the first argument
+// ("zero", "one", "two", "few", "many", "other") or by an exact value ("=0").
+
+// Catalog holds the UI strings of every language a theme provides.
+type Catalog struct {
+	builder *catalog.Builder
+	langs   []string
+}
+
+// Load reads every i18n/*.json file of fsys (normally the merged theme files).
+// A theme without catalogs gets an empty Catalog, which renders every key as
+// its English text.
+func Load(fsys fs.FS) (*Catalog, error) {
+	c := &Catalog{builder: catalog.NewBuilder(catalog.Fallback(language.English))}
+	files, err := fs.Glob(fsys, path.Join(Dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("could not list message catalogs: %w", err)
+	}
+	for _, file := range files {
+		lang := strings.TrimSuffix(path.Base(file), ".json")
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return nil, fmt.Errorf("message catalog '%s' is not named after a language: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read message catalog '%s': %w", file, err)
+		}
+		if err := c.add(tag, data); err != nil {
+			return nil, fmt.Errorf("message catalog '%s': %w", file, err)
+		}
+		c.langs = append(c.langs, tag.String())
+	}
+	slog.Debug("loaded message catalogs", "languages", c.langs)
+	return c, nil
+}
+
+// add parses one catalog file into the builder.
+func (c *Catalog) add(tag language.Tag, data []byte) error {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	for key, raw := range entries {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			if err := c.builder.SetString(tag, key, text); err != nil {
+				return fmt.Errorf("message %q: %w", key, err)
+			}
+			continue
+		}
+
+		var forms map[string]string
+		if err := json.Unmarshal(raw, &forms); err != nil {
+			return fmt.Errorf("message %q must be a string or an object of plural forms", key)
+		}
+		if err := c.builder.Set(tag, key, plural.Selectf(1, "%d", pluralCases(forms)...)); err != nil {
+			return fmt.Errorf("message %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// pluralCategories lists the plural forms in the order they are tried; exact
+// "=N" selectors go before them and "other" always comes last.
+var pluralCategories = []string{"zero", "one", "two", "few", "many"}
+
+// pluralCases turns plural forms into selector/message pairs for plural.Selectf.
+func pluralCases(forms map[string]string) []any {
+	var exact []string
+	for selector := range forms {
+		if strings.HasPrefix(selector, "=") || strings.HasPrefix(selector, "<") {
+			exact = append(exact, selector)
+		}
+	}
+	sort.Strings(exact)
+
+	var cases []any
+	for _, selector := range append(exact, pluralCategories...) {
+		if text, ok := forms[selector]; ok {
+			cases = append(cases, selector, text)
+		}
+	}
+	if text, ok := forms["other"]; ok {
+		cases = append(cases, "other", text)
+	}
+	return cases
+}
+
+// Languages lists the languages the catalog has messages for.
+func (c *Catalog) Languages() []string {
+	if c == nil {
+		return nil
+	}
+	return c.langs
+}
+
+// Printer returns a message printer for lang. Unknown languages print the keys
+// themselves, i.e. English.
+func (c *Catalog) Printer(lang string) *message.Printer {
+	tag, err := language.Parse(strings.ReplaceAll(lang, "_", "-"))
+	if err != nil {
+		tag = language.English
+	}
+	if c == nil {
+		return message.NewPrinter(tag, message.Catalog(catalog.NewBuilder()))
+	}
+	return message.NewPrinter(tag, message.Catalog(c.builder))
+}
+
+// FuncMap returns the translation helper bound to lang:
+//
+//	{{ T "Table of Contents" }}
+//	{{ T "%d chapters" (len .Chapters) }}
+func (c *Catalog) FuncMap(lang string) template.FuncMap {
+	p := c.Printer(lang)
+	return template.FuncMap{
+		"T": func(key string, args ...any) string {
+			return p.Sprintf(key, args...)
+		},
+	}
+}
+
+// Normalize returns lang in canonical BCP 47 form ("es_mx" becomes "es-MX").
+// It reports false when lang is not a valid language tag.
+func Normalize(lang string) (string, bool) {
+	tag, err := language.Parse(strings.ReplaceAll(lang, "_", "-"))
+	if err != nil {
+		return "", false
+	}
+	return tag.String(), true
+}
@@ -0,0 +1,164 @@
+// Package importer splits the text file of a novel into volumes and chapters,
+// the way the C# NovelExtractor does:
+//
+//   - a line matching the volume pattern starts the next volume and restarts
+//     the chapter numbers;
+//   - a line containing the chapter splitter text starts a chapter;
+//   - a line matching the chapter ending pattern ends it and moves on to the
+//     next chapter number;
+//   - lines outside a chapter are skipped.
+//
+// Unlike the extractor, the splitter and ending lines are not part of the
+// chapter, blank lines are kept (the formatter ends paragraphs at them), and a
+// chapter cut short by the next splitter line, volume line or the end of the
+// file is kept and marked Unterminated rather than dropped. Chapters before the
+// first volume line, such as a prologue, go into a volume of their own
+// numbered StartVolumeAt-1, which leaves the first volume line its number as
+// the extractor's volume 0 does.
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// maxLineLength is the longest line Split reads. Some files hold a whole
+// chapter on one line.
+const maxLineLength = 4 << 20
+
+// Options are the markers of a novel's text, as in the extractor's
+// NovelParameters.
+type Options struct {
+	VolumePattern   *regexp.Regexp // Lines starting a volume, e.g. ^VOLUME \d+
+	ChapterSplitter string         // Text of the line starting a chapter
+	ChapterEnding   *regexp.Regexp // Lines ending a chapter, e.g. ^#{4,}
+	StartChapterAt  int            // Number of the first chapter of each volume
+	StartVolumeAt   int            // Number of the first volume
+}
+
+// Volume is a volume found in the text.
+type Volume struct {
+	Number   int
+	Title    string // The volume line, trimmed; "" for chapters before any volume line
+	Line     int    // Line number of the volume line, 0 if there is none
+	Chapters []*Chapter
+}
+
+// Chapter is a chapter found in the text.
+type Chapter struct {
+	Number       int
+	Text         string // "Chapter N" and the chapter's lines, ready for content_plain
+	StartLine    int    // Line number of the splitter line
+	EndLine      int    // Line number of the ending line, or of the last line read
+	Unterminated bool   // No ending line was found before the next chapter, volume or the end of the file
+}
+
+// Split reads the text of a novel from r and returns its volumes in order.
+// Volumes without chapters are left out.
+func Split(r io.Reader, opts Options) ([]*Volume, error) {
+	if opts.VolumePattern == nil || opts.ChapterEnding == nil {
+		return nil, errors.New("volume and chapter ending patterns are required")
+	}
+	if opts.ChapterSplitter == "" {
+		return nil, errors.New("chapter splitter text is required")
+	}
+
+	var volumes []*Volume
+	var volume *Volume
+	var chapter *Chapter
+	var body []string
+	nextVolume := opts.StartVolumeAt
+	nextChapter := opts.StartChapterAt
+
+	startVolume := func(title string, line int) {
+		volume = &Volume{Number: nextVolume, Title: title, Line: line}
+		volumes = append(volumes, volume)
+		nextVolume++
+		nextChapter = opts.StartChapterAt
+	}
+	startPrologue := func() {
+		volume = &Volume{Number: opts.StartVolumeAt - 1}
+		volumes = append(volumes, volume)
+		nextChapter = opts.StartChapterAt
+	}
+	endChapter := func(line int, terminated bool) {
+		if chapter == nil {
+			return
+		}
+		chapter.Text = chapterText(chapter.Number, body)
+		chapter.EndLine = line
+		chapter.Unterminated = !terminated
+		volume.Chapters = append(volume.Chapters, chapter)
+		chapter, body = nil, nil
+		nextChapter++
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff") // Byte order mark left by Windows editors
+		}
+
+		switch {
+		case opts.ChapterEnding.MatchString(line):
+			endChapter(n, true)
+		case opts.VolumePattern.MatchString(line):
+			endChapter(n-1, false)
+			startVolume(strings.TrimSpace(line), n)
+		case strings.Contains(line, opts.ChapterSplitter):
+			endChapter(n-1, false)
+			if volume == nil {
+				startPrologue()
+			}
+			chapter = &Chapter{Number: nextChapter, StartLine: n}
+		case chapter != nil:
+			body = append(body, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read line %d: %w", n+1, err)
+	}
+	endChapter(n, false)
+
+	kept := volumes[:0]
+	for _, v := range volumes {
+		if len(v.Chapters) > 0 {
+			kept = append(kept, v)
+		}
+	}
+	return kept, nil
+}
+
+// chapterText joins the lines of a chapter under the "Chapter N" line the
+// formatter turns into its heading, without blank lines at either end.
+func chapterText(number int, lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Chapter %d\n", number)
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight(line, " \t") + "\n")
+	}
+	return b.String()
+}
+
+// Count returns the number of chapters in volumes.
+func Count(volumes []*Volume) int {
+	n := 0
+	for _, v := range volumes {
+		n += len(v.Chapters)
+	}
+	return n
+}
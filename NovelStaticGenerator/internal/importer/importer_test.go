@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var testOptions = Options{
+	VolumePattern:   regexp.MustCompile(`^VOLUME \d+`),
+	ChapterSplitter: "=== CHAPTER ===",
+	ChapterEnding:   regexp.MustCompile(`^#{4,}`),
+	StartChapterAt:  1,
+	StartVolumeAt:   1,
+}
+
+// split runs Split on the lines and fails the test on an error.
+func split(t *testing.T, opts Options, lines ...string) []*Volume {
+	t.Helper()
+	volumes, err := Split(strings.NewReader(strings.Join(lines, "\n")), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return volumes
+}
+
+func TestSplit(t *testing.T) {
+	volumes := split(t, testOptions,
+		"Title page, skipped",
+		"VOLUME 1: The Start",
+		"=== CHAPTER ===",
+		"First line.",
+		"####",
+		"=== CHAPTER ===",
+		"Second chapter.",
+		"####",
+		"VOLUME 2",
+		"=== CHAPTER ===",
+		"Third chapter.",
+		"####",
+	)
+	if len(volumes) != 2 {
+		t.Fatalf("got %d volumes, want 2", len(volumes))
+	}
+	if v := volumes[0]; v.Number != 1 || v.Title != "VOLUME 1: The Start" || v.Line != 2 || len(v.Chapters) != 2 {
+		t.Errorf("volume 1 = %+v", v)
+	}
+	if v := volumes[1]; v.Number != 2 || v.Title != "VOLUME 2" || v.Line != 9 || len(v.Chapters) != 1 {
+		t.Errorf("volume 2 = %+v", v)
+	}
+	if c := volumes[0].Chapters[1]; c.Number != 2 || c.Text != "Chapter 2\nSecond chapter.\n" || c.StartLine != 6 || c.EndLine != 8 || c.Unterminated {
+		t.Errorf("chapter 2 = %+v", c)
+	}
+	if c := volumes[1].Chapters[0]; c.Number != 1 || c.Text != "Chapter 1\nThird chapter.\n" {
+		t.Errorf("chapter numbers do not restart with the volume: %+v", c)
+	}
+	if Count(volumes) != 3 {
+		t.Errorf("Count = %d, want 3", Count(volumes))
+	}
+}
+
+func TestSplitPrologue(t *testing.T) {
+	volumes := split(t, testOptions,
+		"=== CHAPTER ===",
+		"Prologue.",
+		"####",
+		"VOLUME 1",
+		"=== CHAPTER ===",
+		"One.",
+		"####",
+		"VOLUME 2",
+		"=== CHAPTER ===",
+		"Two.",
+		"####",
+	)
+	want := []struct {
+		number int
+		title  string
+	}{{0, ""}, {1, "VOLUME 1"}, {2, "VOLUME 2"}}
+	if len(volumes) != len(want) {
+		t.Fatalf("got %d volumes, want %d", len(volumes), len(want))
+	}
+	for i, w := range want {
+		if v := volumes[i]; v.Number != w.number || v.Title != w.title || len(v.Chapters) != 1 {
+			t.Errorf("volume %d = %+v, want number %d titled %q", i, v, w.number, w.title)
+		}
+	}
+
+	opts := testOptions
+	opts.StartVolumeAt = 5
+	volumes = split(t, opts, "=== CHAPTER ===", "Prologue.", "####", "VOLUME 1", "=== CHAPTER ===", "One.", "####")
+	if len(volumes) != 2 || volumes[0].Number != 4 || volumes[1].Number != 5 {
+		t.Errorf("with StartVolumeAt 5 the volumes are numbered %d and %d, want 4 and 5", volumes[0].Number, volumes[1].Number)
+	}
+}
+
+func TestSplitUnterminated(t *testing.T) {
+	volumes := split(t, testOptions,
+		"VOLUME 1",
+		"=== CHAPTER ===",
+		"Cut short by the next chapter.",
+		"=== CHAPTER ===",
+		"Cut short by the next volume.",
+		"VOLUME 2",
+		"=== CHAPTER ===",
+		"Cut short by the end of the file.",
+	)
+	if len(volumes) != 2 || len(volumes[0].Chapters) != 2 || len(volumes[1].Chapters) != 1 {
+		t.Fatalf("got %d volumes, want 2 with 2 and 1 chapters", len(volumes))
+	}
+	for _, c := range append(volumes[0].Chapters, volumes[1].Chapters...) {
+		if !c.Unterminated || !strings.Contains(c.Text, "Cut short") {
+			t.Errorf("chapter %+v was not kept as unterminated", c)
+		}
+	}
+	if c := volumes[0].Chapters[0]; c.StartLine != 2 || c.EndLine != 3 {
+		t.Errorf("first chapter spans lines %d-%d, want 2-3", c.StartLine, c.EndLine)
+	}
+	if c := volumes[1].Chapters[0]; c.EndLine != 8 {
+		t.Errorf("last chapter ends at line %d, want 8", c.EndLine)
+	}
+}
+
+func TestSplitBlankLines(t *testing.T) {
+	volumes := split(t, testOptions,
+		"VOLUME 1",
+		"=== CHAPTER ===",
+		"",
+		"First paragraph.  ",
+		"",
+		"",
+		"Second paragraph.",
+		"",
+		"####",
+	)
+	if got, want := volumes[0].Chapters[0].Text, "Chapter 1\nFirst paragraph.\n\n\nSecond paragraph.\n"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+}
+
+func TestSplitEndingOutsideChapter(t *testing.T) {
+	volumes := split(t, testOptions,
+		"VOLUME 1",
+		"####",
+		"Notes between chapters, skipped.",
+		"=== CHAPTER ===",
+		"One.",
+		"####",
+		"####",
+		"=== CHAPTER ===",
+		"Two.",
+		"####",
+	)
+	chapters := volumes[0].Chapters
+	if len(chapters) != 2 || chapters[0].Number != 1 || chapters[1].Number != 2 {
+		t.Fatalf("chapters = %+v, want chapters 1 and 2", chapters)
+	}
+	if strings.Contains(chapters[0].Text, "Notes") {
+		t.Errorf("text outside a chapter was kept: %q", chapters[0].Text)
+	}
+}
+
+func TestSplitBOMAndCRLF(t *testing.T) {
+	text := "\ufeffVOLUME 1\r\n=== CHAPTER ===\r\nOne.\r\n####\r\n"
+	volumes, err := Split(strings.NewReader(text), testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 1 || volumes[0].Title != "VOLUME 1" || volumes[0].Number != 1 {
+		t.Fatalf("volumes = %+v, want VOLUME 1 read past the byte order mark", volumes)
+	}
+	if got, want := volumes[0].Chapters[0].Text, "Chapter 1\nOne.\n"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+}
+
+func TestSplitOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{ChapterSplitter: "x", ChapterEnding: testOptions.ChapterEnding},
+		{ChapterSplitter: "x", VolumePattern: testOptions.VolumePattern},
+		{VolumePattern: testOptions.VolumePattern, ChapterEnding: testOptions.ChapterEnding},
+	} {
+		if _, err := Split(strings.NewReader(""), opts); err == nil {
+			t.Errorf("Split with %+v did not fail", opts)
+		}
+	}
+	if volumes := split(t, testOptions, "VOLUME 1", "No chapters here."); len(volumes) != 0 {
+		t.Errorf("volumes without chapters were kept: %+v", volumes)
+	}
+}
@@ -0,0 +1,242 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This is a deliberately small Markdown renderer covering what our content and
+// novel descriptions use: ATX headings, paragraphs, emphasis, inline code,
+// links, images, flat lists, blockquotes, fenced code and horizontal rules.
+// Raw HTML in the source is escaped by Render; RenderTrusted passes HTML blocks
+// through for files written by the site owners.
+
+var (
+	headingRegex     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	hrRegex          = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	unorderedRegex   = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedRegex     = regexp.MustCompile(`^\s{0,3}\d+[.)]\s+(.*)$`)
+	fenceRegex       = regexp.MustCompile("^\\s{0,3}(```|~~~)\\s*([\\w+-]*)\\s*$")
+	blockquoteRegex  = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	codeSpanRegex    = regexp.MustCompile("`([^`]+)`")
+	imageRegex       = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"([^"]*)")?\)`)
+	linkRegex        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"([^"]*)")?\)`)
+	autolinkRegex    = regexp.MustCompile(`<((?:https?|mailto):[^\s<>]+)>`)
+	strongRegex      = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	emRegex          = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:[^*_]*?\S)?)[*_]($|[^\w*])`)
+	placeholderRegex = regexp.MustCompile("\x00(\\d+)\x00")
+	htmlBlockRegex   = regexp.MustCompile(`^\s{0,3}</?[A-Za-z][A-Za-z0-9-]*(\s|/?>|$)`)
+)
+
+// safeSchemes lists the URL schemes links and images may use. Relative URLs
+// (no scheme) are always allowed.
+var safeSchemes = []string{"http:", "https:", "mailto:"}
+
+// Render converts Markdown source to HTML, escaping any raw HTML.
+func Render(src string) string {
+	return render(src, false)
+}
+
+// RenderTrusted converts Markdown source to HTML, copying blocks that start with
+// an HTML tag through unchanged until the next blank line. Only use it for
+// content written by the site owners.
+func RenderTrusted(src string) string {
+	return render(src, true)
+}
+
+func render(src string, trusted bool) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	renderBlocks(&out, lines, trusted)
+	return strings.TrimSpace(out.String())
+}
+
+// RenderInline converts a single line of Markdown to HTML without wrapping it in a paragraph.
+func RenderInline(src string) string {
+	return renderInline(src)
+}
+
+// renderBlocks renders block-level elements line by line.
+func renderBlocks(out *strings.Builder, lines []string, trusted bool) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			// Two trailing spaces are a hard line break
+			if strings.HasSuffix(line, "  ") && i < len(paragraph)-1 {
+				out.WriteString(renderInline(strings.TrimSpace(line)) + "<br>")
+				continue
+			}
+			out.WriteString(renderInline(strings.TrimSpace(line)))
+		}
+		out.WriteString("</p>\n")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+
+		case trusted && len(paragraph) == 0 && htmlBlockRegex.MatchString(line):
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				out.WriteString(lines[i] + "\n")
+			}
+
+		case fenceRegex.MatchString(line):
+			flush()
+			m := fenceRegex.FindStringSubmatch(line)
+			var code []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != m[1]; i++ {
+				code = append(code, lines[i])
+			}
+			if m[2] != "" {
+				out.WriteString(`<pre><code class="language-` + html.EscapeString(m[2]) + `">`)
+			} else {
+				out.WriteString("<pre><code>")
+			}
+			out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			out.WriteString("</code></pre>\n")
+
+		case headingRegex.MatchString(line):
+			flush()
+			m := headingRegex.FindStringSubmatch(line)
+			level := string(rune('0' + len(m[1])))
+			out.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+
+		case hrRegex.MatchString(line):
+			flush()
+			out.WriteString("<hr>\n")
+
+		case blockquoteRegex.MatchString(line):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && blockquoteRegex.MatchString(lines[i]); i++ {
+				quoted = append(quoted, blockquoteRegex.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			out.WriteString("<blockquote>\n")
+			renderBlocks(out, quoted, trusted)
+			out.WriteString("</blockquote>\n")
+
+		case unorderedRegex.MatchString(line) && len(paragraph) == 0:
+			i = renderList(out, lines, i, unorderedRegex, "ul")
+
+		case orderedRegex.MatchString(line) && len(paragraph) == 0:
+			i = renderList(out, lines, i, orderedRegex, "ol")
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+}
+
+// renderList renders consecutive list items starting at lines[start] and returns
+// the index of the last line consumed.
+func renderList(out *strings.Builder, lines []string, start int, item *regexp.Regexp, tag string) int {
+	out.WriteString("<" + tag + ">\n")
+	i := start
+	for ; i < len(lines); i++ {
+		m := item.FindStringSubmatch(lines[i])
+		if m == nil {
+			// Indented lines continue the previous item
+			if strings.HasPrefix(lines[i], "  ") && strings.TrimSpace(lines[i]) != "" {
+				out.WriteString(" " + renderInline(strings.TrimSpace(lines[i])))
+				continue
+			}
+			break
+		}
+		if i > start {
+			out.WriteString("</li>\n")
+		}
+		out.WriteString("<li>" + renderInline(m[1]))
+	}
+	out.WriteString("</li>\n</" + tag + ">\n")
+	return i - 1
+}
+
+// renderInline renders emphasis, code spans, links and images in one line of text.
+func renderInline(text string) string {
+	// Code spans are rendered first and parked in placeholders so their
+	// content is not touched by the other rules. The placeholders are
+	// delimited by NUL, which has no place in HTML text, so any in the source
+	// is dropped rather than read as one.
+	text = strings.ReplaceAll(text, "\x00", "")
+	var parked []string
+	park := func(s string) string {
+		parked = append(parked, s)
+		return "\x00" + strconv.Itoa(len(parked)-1) + "\x00"
+	}
+	text = codeSpanRegex.ReplaceAllStringFunc(text, func(m string) string {
+		return park("<code>" + html.EscapeString(codeSpanRegex.FindStringSubmatch(m)[1]) + "</code>")
+	})
+
+	text = imageRegex.ReplaceAllStringFunc(text, func(m string) string {
+		sub := imageRegex.FindStringSubmatch(m)
+		if !isSafeURL(sub[2]) {
+			return sub[1]
+		}
+		tag := `<img src="` + html.EscapeString(sub[2]) + `" alt="` + html.EscapeString(sub[1]) + `"`
+		if sub[3] != "" {
+			tag += ` title="` + html.EscapeString(sub[3]) + `"`
+		}
+		return park(tag + ">")
+	})
+	// Only the tags of a link are parked; its text stays in the stream so it
+	// is escaped and can carry emphasis like any other text.
+	text = linkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		sub := linkRegex.FindStringSubmatch(m)
+		if !isSafeURL(sub[2]) {
+			return sub[1]
+		}
+		tag := `<a href="` + html.EscapeString(sub[2]) + `"`
+		if sub[3] != "" {
+			tag += ` title="` + html.EscapeString(sub[3]) + `"`
+		}
+		return park(tag+">") + sub[1] + park("</a>")
+	})
+	text = autolinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		href := html.EscapeString(autolinkRegex.FindStringSubmatch(m)[1])
+		return park(`<a href="` + href + `">` + href + "</a>")
+	})
+
+	text = html.EscapeString(text)
+
+	text = strongRegex.ReplaceAllString(text, "<strong>$2</strong>")
+	text = emRegex.ReplaceAllString(text, "$1<em>$2</em>$3")
+
+	// Placeholders may nest (a link around an image), so expand until stable.
+	for placeholderRegex.MatchString(text) {
+		text = placeholderRegex.ReplaceAllStringFunc(text, func(m string) string {
+			n, _ := strconv.Atoi(placeholderRegex.FindStringSubmatch(m)[1])
+			return parked[n]
+		})
+	}
+	return text
+}
+
+// isSafeURL rejects URLs with schemes such as javascript: or data:.
+func isSafeURL(u string) bool {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	colon := strings.Index(lower, ":")
+	if colon < 0 || strings.ContainsAny(lower[:colon], "/?#") {
+		return true // relative URL
+	}
+	for _, scheme := range safeSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	return false
+}
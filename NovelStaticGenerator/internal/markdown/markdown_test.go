@@ -0,0 +1,37 @@
+package markdown
+
+import "testing"
+
+func TestRenderInline(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain <b>text</b> & more", "plain &lt;b&gt;text&lt;/b&gt; &amp; more"},
+		{"**strong** and *em*", "<strong>strong</strong> and <em>em</em>"},
+		{"`a *b* <c>`", "<code>a *b* &lt;c&gt;</code>"},
+		{"[a *link*](https://example.com)", `<a href="https://example.com">a <em>link</em></a>`},
+		{"[bad](javascript:void)", "bad"},
+		{"![alt](img.png)", `<img src="img.png" alt="alt">`},
+		{"[![alt](img.png)](/to)", `<a href="/to"><img src="img.png" alt="alt"></a>`},
+		{"<https://example.com>", `<a href="https://example.com">https://example.com</a>`},
+		{"hello \x003\x00 world", "hello 3 world"},
+		{"`\x000\x00` [x](/y) \x001\x00", "<code>0</code> <a href=\"/y\">x</a> 1"},
+	}
+	for _, tt := range tests {
+		if got := RenderInline(tt.in); got != tt.want {
+			t.Errorf("RenderInline(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	in := "# Title\n\nA paragraph  \nwith a break.\n\n- one\n- two\n\n<div>raw</div>\n\n```go\nx := 1 < 2\n```"
+	want := "<h1>Title</h1>\n<p>A paragraph<br>\nwith a break.</p>\n<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n" +
+		"<p>&lt;div&gt;raw&lt;/div&gt;</p>\n<pre><code class=\"language-go\">x := 1 &lt; 2</code></pre>"
+	if got := Render(in); got != want {
+		t.Errorf("Render:\n got %q\nwant %q", got, want)
+	}
+	if got, want := RenderTrusted("<div>raw</div>"), "<div>raw</div>"; got != want {
+		t.Errorf("RenderTrusted = %q, want %q", got, want)
+	}
+}
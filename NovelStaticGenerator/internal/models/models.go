@@ -1,50 +1,242 @@
 package models
 
-import "html/template" // Import html/template
+import (
+	"NovelStaticGenerator/internal/sanitize"
+	"html/template" // Import html/template
+	"time"
+)
 
 // Chapter represents a single chapter fetched from the database.
 // Note: Adjust field types (e.g., int vs int64) based on your DB schema's exact integer sizes.
 type Chapter struct {
 	ID            int    `db:"id"`             // Database primary key
+	NovelID       int    `db:"novel_id"`       // Owning novel
+	VolumeID      int    `db:"volume_id"`      // Owning volume
 	NovelName     string `db:"novel_name"`     // Name of the novel
 	ChapterNumber int    `db:"chapter_number"` // Sequence number of the chapter
 	VolumeNumber  int    `db:"volume_number"`
-	// Title         string `db:"title"` // Optional: Add if you have a chapter title column
-	ContentHTML  template.HTML `db:"content_html"`  // Pre-rendered plain HTML (Use template.HTML to prevent escaping)
-	ContentBulma template.HTML `db:"content_bulma"` // Pre-rendered Bulma HTML (Use template.HTML)
+	Title         string `db:"title"` // Optional chapter title (empty if unset)
+
+	Content   map[string]template.HTML // Pre-rendered HTML by content column, e.g. "content_bulma" (template.HTML prevents escaping)
+	Plain     string                   // Unformatted text (content_plain), loaded only for variants with a format
+	CreatedAt time.Time                `db:"created_at"` // Zero if unset in the DB
+	UpdatedAt time.Time                `db:"updated_at"` // Zero if unset in the DB
 
 	// --- Fields added for generation logic ---
-	NovelSlug     string   // URL-friendly version of NovelName
-	FilenameHTML  string   // Output filename for plain HTML version
-	FilenameBulma string   // Output filename for Bulma version
-	PrevChapter   *Chapter // Pointer to the previous chapter (nil if none)
-	NextChapter   *Chapter // Pointer to the next chapter (nil if none)
+	NovelSlug   string            // URL-friendly version of NovelName
+	TitleSlug   string            // URL-friendly version of Title, unique within the permalink pattern
+	Path        string            // Output path of the primary variant, relative to the site root
+	URL         string            // Site-relative link to the primary variant
+	Paths       map[string]string // Output path by variant name
+	URLs        map[string]string // Site-relative link by variant name
+	PrevChapter *Chapter          // Pointer to the previous chapter (nil if none)
+	NextChapter *Chapter          // Pointer to the next chapter (nil if none)
+	Characters  []*Character      // Glossary characters named in the chapter, by name
+	TOC         []*TOCEntry       // Headings and scene breaks of the primary variant's content
+	Stats       Stats             // Length of the primary variant's text
+}
+
+// Stats is the length of a chapter, or the totals of a volume, novel or site.
+type Stats struct {
+	Chapters       int // Chapters counted (1 for a chapter)
+	Words          int
+	Chars          int // Characters other than whitespace
+	ReadingMinutes int // Estimated reading time, at least 1 when anything was counted
+}
+
+// TOCEntry is a heading or scene break of a chapter, linked by its id.
+type TOCEntry struct {
+	ID    string // Fragment identifier, e.g. "the-duel" or "scene-2"
+	Title string // Heading text; "" for scene breaks
+	Level int    // Heading level 1-6; 0 for scene breaks
+	Scene int    // Number of the scene a scene break starts; 0 for headings
+}
+
+// Variant is one rendering of every chapter, such as the plain HTML version or
+// the Bulma-styled one. Each chapter is written once per variant.
+type Variant struct {
+	Name        string            `json:"name"`        // Identifier used by chapterURL, e.g. "styled"
+	Label       string            `json:"label"`       // Link text in chapter lists (translated with T)
+	Content     string            `json:"content"`     // Chapter content column rendered, e.g. "content_bulma"
+	Format      string            `json:"format"`      // Formatter style applied to content_plain instead, e.g. "styled"
+	Suffix      string            `json:"suffix"`      // Filename suffix, the {variant} permalink placeholder
+	Stylesheets []string          `json:"stylesheets"` // Static files linked from the variant's pages
+	Template    string            `json:"template"`    // Page template for its chapters (default "chapter")
+	Params      map[string]string `json:"params"`      // Free-form settings for templates, e.g. button_class
+	Footnotes   string            `json:"footnotes"`   // How notes are shown: "endnotes" (default) or "popovers"
+	Sanitize    *sanitize.Policy  `json:"sanitize"`    // HTML allowed in its stored content (nil: sanitize.DefaultPolicy)
+}
+
+// Volume represents one volume of a novel and the chapters it contains.
+type Volume struct {
+	ID          int    `db:"volume_id"`
+	NovelID     int    `db:"novel_id"`
+	Number      int    `db:"volume_number"`
+	Title       string `db:"title"`       // Optional volume title (empty if unset)
+	Description string `db:"description"` // Optional volume description
+
+	// --- Fields added for generation logic ---
+	Path     string     // Output path of the volume index, relative to the site root
+	URL      string     // Site-relative link to the volume index
+	Chapters []*Chapter // Sorted list of chapters in this volume
+	Stats    Stats      // Totals of its chapters
 }
 
 // Novel represents a collection of chapters for a single novel.
 type Novel struct {
-	Name     string
-	Slug     string
-	Chapters []*Chapter // Sorted list of chapters
+	ID          int    `db:"novel_id"`
+	Name        string `db:"name"`
+	Author      string `db:"author"`
+	Status      string `db:"status"` // ongoing, finished or hiatus
+	Description string `db:"description"`
+	Lang        string `db:"lang"` // Language of the novel's text; defaults to the site language
+	Slug        string
+	Path        string     // Output path of the novel landing page, relative to the site root
+	URL         string     // Site-relative link to the novel landing page
+	Volumes     []*Volume  // Sorted list of volumes
+	Chapters    []*Chapter // Sorted list of chapters
+	Stats       Stats      // Totals of its chapters
+}
+
+// Character is an entry of the character glossary.
+type Character struct {
+	ID          int      `db:"character_id"`
+	Name        string   `db:"name"`
+	Aliases     []string `db:"aliases"`     // Other names the character goes by (comma-separated in the DB)
+	Description string   `db:"description"` // Markdown
+
+	// --- Fields added for generation logic ---
+	Slug        string
+	Path        string     // Output path of the character page, relative to the site root
+	URL         string     // Site-relative link to the character page
+	Appearances []*Chapter // Chapters naming the character, in reading order
+}
+
+// Page is a standalone content page built from a Markdown file in content/.
+type Page struct {
+	Title       string
+	Slug        string // Output location relative to the site root, e.g. "about" or "help/faq"
+	Description string
+	Layout      string            // Page template to render with (default "page")
+	Menu        bool              // Whether the page is listed in the site navigation menu
+	Weight      int               // Menu order, lower first
+	Draft       bool              // Drafts are skipped
+	Lang        string            // Language of the page; defaults to the site language
+	Params      map[string]string // Any other front matter keys
+	Content     template.HTML     // Rendered Markdown body
+	Source      string            // Path of the Markdown file
+
+	// --- Fields added for generation logic ---
+	Path string // Output path, relative to the site root
+	URL  string // Site-relative link
+}
+
+// Site holds data shared by every page.
+type Site struct {
+	Title    string
+	Lang     string     // Default language (BCP 47 tag, e.g. "es")
+	Menu     []*Page    // Content pages shown in the navigation menu, sorted by weight
+	Variants []*Variant // Every chapter variant; the first is the primary one
+
+	// Character glossary, sorted by name, and the site-relative link to its
+	// index ("" when there are no characters)
+	Characters    []*Character
+	CharactersURL string
+
+	StatsURL  string // Site-relative link to the statistics page
+	SearchURL string // Site-relative link to the search page ("" when search is off)
 }
 
 // IndexPageData holds data needed for the main index.html template.
 type IndexPageData struct {
-	Novels        []*Novel
-	IsBulmaStyled bool // <<<--- ADD THIS FIELD
-    // SiteBasePath isn't strictly needed here, but adding it
-    // for consistency with _base.html might prevent future issues
-    // if base template uses it elsewhere. Let's add it for safety.
-    SiteBasePath string // <<<--- ADD THIS FIELD (Optional but recommended)
+	Site         *Site
+	Novels       []*Novel
+	Variant      *Variant // Variant the page is rendered in (the primary one outside chapters)
+	SiteBasePath string   // Relative path from the page back to the site root
+	Lang         string   // Language of the page, used for <html lang> and T
+}
 
+// NovelPageData holds data needed for a novel landing page (novel.html).
+type NovelPageData struct {
+	Site         *Site
+	Novel        *Novel
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
+}
+
+// VolumePageData holds data needed for a volume index page (volume.html).
+type VolumePageData struct {
+	Site         *Site
+	Novel        *Novel
+	Volume       *Volume
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
 }
 
 // ChapterPageData holds data needed for the chapter.html template.
 type ChapterPageData struct {
-	NovelName     string
-	NovelSlug     string
-	Current       *Chapter
-	IsBulmaStyled bool // Field already exists here
-	SiteBasePath  string // Field already exists here
+	Site         *Site
+	NovelName    string
+	NovelSlug    string
+	Novel        *Novel
+	Current      *Chapter
+	Content      template.HTML // Current's content for the variant
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
+}
+
+// CharacterPageData holds data needed for a character page (character.html).
+type CharacterPageData struct {
+	Site         *Site
+	Character    *Character
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
+}
+
+// CharactersPageData holds data needed for the glossary index (characters.html).
+type CharactersPageData struct {
+	Site         *Site
+	Characters   []*Character
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
+}
+
+// NovelStats is one novel's part of the statistics page.
+type NovelStats struct {
+	Novel        *Novel
+	LengthChart  template.HTML // Words per chapter, as inline SVG
+	CadenceChart template.HTML // Chapters per month of created_at, as inline SVG ("" when no chapter has a date)
+}
+
+// StatsPageData holds data needed for the statistics page (stats.html).
+type StatsPageData struct {
+	Site         *Site
+	Novels       []*NovelStats
+	Totals       Stats // Of every novel
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
+}
+
+// SearchPageData holds data needed for the search page (search.html).
+type SearchPageData struct {
+	Site         *Site
+	IndexURL     string // Site-relative link to the search index manifest
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
+}
 
+// ContentPageData holds data needed for a content page template (page.html by default).
+type ContentPageData struct {
+	Site         *Site
+	Page         *Page
+	Variant      *Variant
+	SiteBasePath string
+	Lang         string
 }
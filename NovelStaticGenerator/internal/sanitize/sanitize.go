@@ -0,0 +1,256 @@
+package sanitize
+
+import (
+	"NovelStaticGenerator/internal/htmltoken"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// Policy lists what chapter HTML may contain. Anything else is removed: tags
+// not listed are dropped while their text is kept (except for the content of
+// elements such as <script> and <style>), attributes not listed are dropped,
+// and URL attributes whose scheme is not listed are dropped. Relative URLs are
+// always allowed and on* event handler attributes never are.
+type Policy struct {
+	Tags       []string            `json:"tags"`       // Allowed elements, e.g. "p", "strong"
+	Attributes map[string][]string `json:"attributes"` // Allowed attributes by element; "*" applies to all
+	Schemes    []string            `json:"schemes"`    // Allowed URL schemes, e.g. "https"
+}
+
+// DefaultPolicy allows the text-level and structural markup found in chapters,
+// classes and ids for styling, links, and images, with http, https and mailto
+// URLs.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Tags: []string{
+			"a", "abbr", "article", "aside", "b", "bdi", "bdo", "blockquote", "br", "caption", "center", "cite",
+			"code", "col", "colgroup", "dd", "del", "details", "dfn", "div", "dl", "dt", "em", "figcaption",
+			"figure", "footer", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hr", "i", "img", "ins", "kbd",
+			"li", "mark", "ol", "p", "pre", "q", "rp", "rt", "ruby", "s", "samp", "section", "small", "span",
+			"strong", "sub", "summary", "sup", "table", "tbody", "td", "tfoot", "th", "thead", "time", "tr",
+			"u", "ul", "var", "wbr",
+		},
+		Attributes: map[string][]string{
+			"*":          {"class", "id", "title", "lang", "dir"},
+			"a":          {"href", "name", "rel", "hreflang"},
+			"img":        {"src", "srcset", "alt", "width", "height", "loading"},
+			"blockquote": {"cite"},
+			"q":          {"cite"},
+			"del":        {"cite", "datetime"},
+			"ins":        {"cite", "datetime"},
+			"time":       {"datetime"},
+			"ol":         {"start", "reversed", "type"},
+			"li":         {"value"},
+			"td":         {"colspan", "rowspan", "headers"},
+			"th":         {"colspan", "rowspan", "headers", "scope", "abbr"},
+			"col":        {"span"},
+			"colgroup":   {"span"},
+			"details":    {"open"},
+		},
+		Schemes: []string{"http", "https", "mailto"},
+	}
+}
+
+// urlAttributes hold URLs whose scheme is checked.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true, "formaction": true, "poster": true,
+	"background": true, "longdesc": true, "usemap": true, "xlink:href": true, "data": true,
+}
+
+// dropContent lists elements whose content goes with them when they are not
+// allowed: scripts, styles and embedded documents are not text to keep.
+var dropContent = map[string]bool{
+	"script": true, "style": true, "template": true, "iframe": true, "object": true,
+	"noscript": true, "noembed": true, "noframes": true, "xmp": true, "plaintext": true, "textarea": true, "title": true,
+}
+
+// Sanitizer applies a Policy. It is safe for concurrent use.
+type Sanitizer struct {
+	tags    map[string]bool
+	attrs   map[string]map[string]bool
+	schemes map[string]bool
+}
+
+// New compiles p; a nil p selects DefaultPolicy.
+func New(p *Policy) *Sanitizer {
+	if p == nil {
+		p = DefaultPolicy()
+	}
+	s := &Sanitizer{
+		tags:    make(map[string]bool, len(p.Tags)),
+		attrs:   make(map[string]map[string]bool, len(p.Attributes)),
+		schemes: make(map[string]bool, len(p.Schemes)),
+	}
+	for _, tag := range p.Tags {
+		s.tags[strings.ToLower(tag)] = true
+	}
+	for tag, names := range p.Attributes {
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			set[strings.ToLower(name)] = true
+		}
+		s.attrs[strings.ToLower(tag)] = set
+	}
+	for _, scheme := range p.Schemes {
+		s.schemes[strings.ToLower(strings.TrimSuffix(scheme, ":"))] = true
+	}
+	return s
+}
+
+// Report counts what Sanitize removed by description, e.g. "<script>",
+// "onclick attribute on <a>" or "javascript: URL in href on <a>".
+type Report map[string]int
+
+// String lists the removals, most frequent first.
+func (r Report) String() string {
+	keys := make([]string, 0, len(r))
+	for key := range r {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if r[keys[i]] != r[keys[j]] {
+			return r[keys[i]] > r[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s ×%d", key, r[key])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Sanitize returns src with everything the policy does not allow removed, and
+// what was removed. Elements left open are closed and stray end tags dropped,
+// so the result cannot break the markup of the page it is inserted into.
+func (s *Sanitizer) Sanitize(src string) (string, Report) {
+	var out strings.Builder
+	out.Grow(len(src))
+	report := Report{}
+	var open []string    // Allowed elements not closed yet
+	skip, depth := "", 0 // Element whose content is being dropped, and how deep in it
+
+	z := htmltoken.New(src)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			break
+		}
+		if skip != "" {
+			switch {
+			case tok.Type == htmltoken.StartTag && tok.Data == skip && !tok.SelfClosing:
+				depth++
+			case tok.Type == htmltoken.EndTag && tok.Data == skip:
+				if depth--; depth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+
+		switch tok.Type {
+		case htmltoken.Text, htmltoken.RawText:
+			out.WriteString(strings.ReplaceAll(tok.Data, "<", "&lt;"))
+
+		case htmltoken.Comment:
+			report["comment"]++
+
+		case htmltoken.StartTag:
+			if !s.tags[tok.Data] {
+				report["<"+tok.Data+">"]++
+				if dropContent[tok.Data] && !tok.SelfClosing {
+					skip, depth = tok.Data, 1
+				}
+				continue
+			}
+			kept := tok.Attrs[:0]
+			for _, attr := range tok.Attrs {
+				if reason := s.rejectAttribute(tok.Data, attr); reason != "" {
+					report[reason]++
+					continue
+				}
+				kept = append(kept, attr)
+			}
+			tok.Attrs = kept
+			out.WriteString(tok.TagString())
+			if !htmltoken.IsVoid(tok.Data) && !tok.SelfClosing {
+				open = append(open, tok.Data)
+			}
+
+		case htmltoken.EndTag:
+			i := len(open) - 1
+			for i >= 0 && open[i] != tok.Data {
+				i--
+			}
+			if i < 0 {
+				continue // Stray, or the end of a removed element
+			}
+			for j := len(open) - 1; j >= i; j-- {
+				out.WriteString("</" + open[j] + ">")
+			}
+			open = open[:i]
+		}
+	}
+	for j := len(open) - 1; j >= 0; j-- {
+		out.WriteString("</" + open[j] + ">")
+	}
+	return out.String(), report
+}
+
+// rejectAttribute returns why attr may not stay on tag, or "" if it may.
+func (s *Sanitizer) rejectAttribute(tag string, attr htmltoken.Attribute) string {
+	if strings.HasPrefix(attr.Name, "on") || !validAttributeName(attr.Name) || !(s.attrs[tag][attr.Name] || s.attrs["*"][attr.Name]) {
+		return fmt.Sprintf("%s attribute on <%s>", attr.Name, tag)
+	}
+	if attr.Name == "srcset" {
+		for _, candidate := range strings.Split(attr.Value, ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				if scheme, ok := s.allowedURL(fields[0]); !ok {
+					return fmt.Sprintf("%s: URL in %s on <%s>", scheme, attr.Name, tag)
+				}
+			}
+		}
+	}
+	if urlAttributes[attr.Name] {
+		if scheme, ok := s.allowedURL(attr.Value); !ok {
+			return fmt.Sprintf("%s: URL in %s on <%s>", scheme, attr.Name, tag)
+		}
+	}
+	return ""
+}
+
+// allowedURL reports whether the raw attribute value is a relative URL or one
+// with an allowed scheme, returning the scheme found. Browsers ignore control
+// characters and whitespace inside schemes ("java\tscript:"), so they are
+// removed before looking.
+func (s *Sanitizer) allowedURL(value string) (string, bool) {
+	v := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, html.UnescapeString(value))
+	i := strings.IndexAny(v, ":/?#")
+	if i < 0 || v[i] != ':' {
+		return "", true
+	}
+	scheme := strings.ToLower(v[:i])
+	return scheme, s.schemes[scheme]
+}
+
+// validAttributeName rejects names a browser would not read as written, such
+// as the leftovers of broken quoting.
+func validAttributeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == ':' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package sanitize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		want   string
+		report Report
+	}{
+		{
+			name: "allowed markup",
+			in:   `<p class="note" id="a">Some <em>text</em> &amp; <a href="https://example.com/x?a=1&amp;b=2" title="T">a link</a><br></p>`,
+			want: `<p class="note" id="a">Some <em>text</em> &amp; <a href="https://example.com/x?a=1&amp;b=2" title="T">a link</a><br /></p>`,
+		},
+		{
+			name: "relative and allowed URLs",
+			in:   `<a href="/v1-c2.html#top">a</a><a href="mailto:me@example.com">b</a><img src="img/a.png" alt="x">`,
+			want: `<a href="/v1-c2.html#top">a</a><a href="mailto:me@example.com">b</a><img src="img/a.png" alt="x" />`,
+		},
+		{
+			name:   "javascript URL",
+			in:     `<a href="javascript:alert(1)">x</a>`,
+			want:   `<a>x</a>`,
+			report: Report{"javascript: URL in href on <a>": 1},
+		},
+		{
+			name:   "upper-case javascript URL with spaces",
+			in:     `<a href="  JavaScript:alert(1)">x</a>`,
+			want:   `<a>x</a>`,
+			report: Report{"javascript: URL in href on <a>": 1},
+		},
+		{
+			name:   "data URL",
+			in:     `<img src="data:image/svg+xml;base64,PHN2Zz4=" alt="x">`,
+			want:   `<img alt="x" />`,
+			report: Report{"data: URL in src on <img>": 1},
+		},
+		{
+			name:   "data URL in srcset",
+			in:     `<img srcset="a.png 1x, data:image/png;base64,AAAA 2x">`,
+			want:   `<img />`,
+			report: Report{"data: URL in srcset on <img>": 1},
+		},
+		{
+			name: "entity-encoded schemes",
+			in: `<a href="&#106;avascript:alert(1)">a</a>` +
+				`<a href="&#x6A;avascript:alert(1)">b</a>` +
+				`<a href="javascript&colon;alert(1)">c</a>` +
+				`<a href="java&#x09;script:alert(1)">d</a>` +
+				"<a href=\"java\nscript:alert(1)\">e</a>",
+			want:   `<a>a</a><a>b</a><a>c</a><a>d</a><a>e</a>`,
+			report: Report{"javascript: URL in href on <a>": 5},
+		},
+		{
+			name:   "event handlers",
+			in:     `<p onclick="x()" ONMOUSEOVER='y()'>a</p><img src=a.png onerror=alert(1)>`,
+			want:   `<p>a</p><img src="a.png" />`,
+			report: Report{"onclick attribute on <p>": 1, "onmouseover attribute on <p>": 1, "onerror attribute on <img>": 1},
+		},
+		{
+			name:   "attributes not in the policy",
+			in:     `<p style="color:red" data-x="1">a</p>`,
+			want:   `<p>a</p>`,
+			report: Report{"style attribute on <p>": 1, "data-x attribute on <p>": 1},
+		},
+		{
+			name:   "script and style bodies",
+			in:     `<p>a</p><script>alert("<p>x</p>")</script><style>p{color:red}</style><SCRIPT type="text/javascript">b()</SCRIPT ><p>c</p>`,
+			want:   `<p>a</p><p>c</p>`,
+			report: Report{"<script>": 2, "<style>": 1},
+		},
+		{
+			name:   "unclosed script",
+			in:     `<p>a</p><script>alert(1)<p>b</p>`,
+			want:   `<p>a</p>`,
+			report: Report{"<script>": 1},
+		},
+		{
+			name:   "disallowed tags keep their text",
+			in:     `<font color="red">red</font> <marquee>moving</marquee>`,
+			want:   `red moving`,
+			report: Report{"<font>": 1, "<marquee>": 1},
+		},
+		{
+			name:   "iframe content is dropped",
+			in:     `<iframe src="https://evil.example">fallback</iframe>after`,
+			want:   `after`,
+			report: Report{"<iframe>": 1},
+		},
+		{
+			name:   "comments",
+			in:     `a<!-- <script>x</script> -->b<!doctype html>c`,
+			want:   `abc`,
+			report: Report{"comment": 2},
+		},
+		{
+			name: "unclosed and misnested elements",
+			in:   `<p><em>a<strong>b</em>c`,
+			want: `<p><em>a<strong>b</strong></em>c</p>`,
+		},
+		{
+			name: "stray end tags",
+			in:   `</div>a</p><p>b</span></p>`,
+			want: `a<p>b</p>`,
+		},
+		{
+			name:   "malformed tags",
+			in:     `1 < 2 <3 <> </ x> <p <em>`,
+			want:   `1 &lt; 2 &lt;3 &lt;>  <p></p>`,
+			report: Report{"<em attribute on <p>": 1, "comment": 1}, // "</ x>" is read as a bogus comment
+		},
+		{
+			name:   "broken attribute quoting",
+			in:     `<img src="a.png"onerror="alert(1)" alt=x"y>`,
+			want:   `<img src="a.png" alt="x&#34;y" />`,
+			report: Report{"onerror attribute on <img>": 1},
+		},
+		{
+			name: "unterminated attribute",
+			in:   `<a href="https://example.com>text`,
+			want: `<a href="https://example.com&gt;text"></a>`,
+		},
+		{
+			name:   "unterminated attribute with a scheme",
+			in:     `<a title="x" href='javascript:alert(1)>text`,
+			want:   `<a title="x"></a>`,
+			report: Report{"javascript: URL in href on <a>": 1},
+		},
+		{
+			name: "quotes and brackets in attribute values are re-encoded",
+			in:   `<span title='a"><script>alert(1)</script>'>x</span>`,
+			want: `<span title="a&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">x</span>`,
+		},
+	}
+	s := New(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, report := s.Sanitize(tt.in)
+			if got != tt.want {
+				t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+			if tt.report == nil {
+				tt.report = Report{}
+			}
+			if !reflect.DeepEqual(report, tt.report) {
+				t.Errorf("report = %v, want %v", report, tt.report)
+			}
+		})
+	}
+}
+
+func TestSanitizePolicy(t *testing.T) {
+	s := New(&Policy{
+		Tags:       []string{"P", "a", "img"},
+		Attributes: map[string][]string{"a": {"HREF"}, "img": {"src"}},
+		Schemes:    []string{"https:", "data"},
+	})
+	got, report := s.Sanitize(`<p class="x"><a href="http://example.com">a</a><a href="HTTPS://example.com">b</a><img src="data:image/png;base64,AAAA"><em>c</em></p>`)
+	want := `<p><a>a</a><a href="HTTPS://example.com">b</a><img src="data:image/png;base64,AAAA" />c</p>`
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	wantReport := Report{"class attribute on <p>": 1, "http: URL in href on <a>": 1, "<em>": 1}
+	if !reflect.DeepEqual(report, wantReport) {
+		t.Errorf("report = %v, want %v", report, wantReport)
+	}
+}
+
+func TestSanitizeOnAttributesAlwaysRemoved(t *testing.T) {
+	s := New(&Policy{Tags: []string{"p"}, Attributes: map[string][]string{"*": {"onclick"}}})
+	got, report := s.Sanitize(`<p onclick="x()">a</p>`)
+	if got != `<p>a</p>` || report["onclick attribute on <p>"] != 1 {
+		t.Errorf("got %q, %v; want the handler removed", got, report)
+	}
+}
+
+func TestReportString(t *testing.T) {
+	r := Report{"<script>": 1, "onclick attribute on <p>": 3, "comment": 1}
+	want := "onclick attribute on <p> ×3, <script> ×1, comment ×1"
+	if got := r.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (Report{}).String(); got != "" {
+		t.Errorf("empty String() = %q, want \"\"", got)
+	}
+}
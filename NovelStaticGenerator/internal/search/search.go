@@ -0,0 +1,171 @@
+// Package search builds the inverted index the search page queries in the
+// browser. The index is split into shards by the first two letters of each
+// term, so a query only downloads the shards of its own words:
+//
+//	search/index.json  {"version": 1, "docs": [[url, title, excerpt], …], "shards": ["ab", "sa", …]}
+//	search/sa.json     {"saga": [[doc, position, gap, gap…], …], …}
+//
+// Positions count the words of a document from 0 and are stored as gaps from
+// the previous one, which keeps the numbers small; they let the client match
+// phrases. Terms are folded like slugs ("Tío" is indexed as "tio") and
+// static/js/search.js must tokenize queries the same way.
+package search
+
+import (
+	"NovelStaticGenerator/internal/utils"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Version is the format version written into index.json.
+const Version = 1
+
+const (
+	minTermLength = 2  // Shorter words ("a", "y") are not indexed
+	maxTermLength = 40 // Longer "words" are usually not words
+	shardPrefix   = 2  // Runes of a term naming its shard
+)
+
+// Document is one searchable page.
+type Document struct {
+	URL     string // Site-relative link
+	Title   string
+	Excerpt string // Shown under the title in the results
+	Text    string // Plain text to index
+}
+
+// Token is a term of a text and the position of its word.
+type Token struct {
+	Term     string
+	Position int
+}
+
+// Tokenize folds text and splits it into terms at everything that is not a
+// letter or digit. Words too short or too long to index still take a position,
+// so phrases around them match.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	position := 0
+	for _, word := range strings.FieldsFunc(utils.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if n := utf8.RuneCountInString(word); minTermLength <= n && n <= maxTermLength {
+			tokens = append(tokens, Token{Term: word, Position: position})
+		}
+		position++
+	}
+	return tokens
+}
+
+// ShardName returns the name of the shard holding term: its first two runes
+// when those are ASCII letters or digits, "_" and their UTF-8 bytes in hex
+// otherwise ("_c3a7" for "ça…"), which stays a safe file name.
+func ShardName(term string) string {
+	prefix := term
+	if i := runeOffset(term, shardPrefix); i >= 0 {
+		prefix = term[:i]
+	}
+	for _, r := range prefix {
+		if !('a' <= r && r <= 'z') && !('0' <= r && r <= '9') {
+			return "_" + hex.EncodeToString([]byte(prefix))
+		}
+	}
+	return prefix
+}
+
+// runeOffset returns the byte offset of the n-th rune of s, or -1 when s is
+// shorter.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return -1
+}
+
+// Index is an inverted index under construction.
+type Index struct {
+	docs     [][3]string
+	postings map[string][][]int // Term -> one [doc, positions as gaps…] entry per document
+}
+
+// New returns an empty index.
+func New() *Index {
+	return &Index{postings: make(map[string][][]int)}
+}
+
+// Add indexes doc.
+func (ix *Index) Add(doc Document) {
+	id := len(ix.docs)
+	ix.docs = append(ix.docs, [3]string{doc.URL, doc.Title, doc.Excerpt})
+
+	positions := make(map[string][]int)
+	var order []string
+	for _, token := range Tokenize(doc.Text) {
+		if _, seen := positions[token.Term]; !seen {
+			order = append(order, token.Term)
+		}
+		positions[token.Term] = append(positions[token.Term], token.Position)
+	}
+	for _, term := range order {
+		entry := []int{id}
+		last := 0
+		for _, position := range positions[term] {
+			entry = append(entry, position-last)
+			last = position
+		}
+		ix.postings[term] = append(ix.postings[term], entry)
+	}
+}
+
+// Terms returns the number of distinct terms indexed.
+func (ix *Index) Terms() int {
+	return len(ix.postings)
+}
+
+// Files returns the JSON files of the index by name: index.json and one file
+// per shard.
+func (ix *Index) Files() (map[string][]byte, error) {
+	shards := make(map[string]map[string][][]int)
+	for term, entries := range ix.postings {
+		name := ShardName(term)
+		if shards[name] == nil {
+			shards[name] = make(map[string][][]int)
+		}
+		shards[name][term] = entries
+	}
+
+	names := make([]string, 0, len(shards))
+	files := make(map[string][]byte, len(shards)+1)
+	for name, terms := range shards {
+		data, err := json.Marshal(terms) // Map keys are sorted, so builds are reproducible
+		if err != nil {
+			return nil, fmt.Errorf("could not encode search shard '%s': %w", name, err)
+		}
+		files[name+".json"] = data
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	docs := ix.docs
+	if docs == nil {
+		docs = [][3]string{}
+	}
+	manifest, err := json.Marshal(struct {
+		Version int         `json:"version"`
+		Docs    [][3]string `json:"docs"`
+		Shards  []string    `json:"shards"`
+	}{Version, docs, names})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode search index: %w", err)
+	}
+	files["index.json"] = manifest
+	return files, nil
+}
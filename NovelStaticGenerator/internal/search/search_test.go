@@ -0,0 +1,172 @@
+package search
+
+import (
+	"NovelStaticGenerator/internal/utils"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// tokenizeCases are shared by the Go tests and the comparison with search.js.
+var tokenizeCases = []string{
+	"Saga drew the katana.",
+	"Tío Ñandú, ¿qué tal? ÉL DIJO: «ça va»",
+	"Café vs Café",
+	"a b cd — e-f g_h 3.14 x2",
+	"Mamaru-san's 1st \"quote\"",
+	"日本語のテキスト 東京",
+	"Ωμέγα ΣΟΦΊΑ",
+	"emoji 🎉 here",
+	strings.Repeat("x", 40) + " " + strings.Repeat("y", 41) + " z end",
+	"",
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Token
+	}{
+		{"Saga drew the katana.", []Token{{"saga", 0}, {"drew", 1}, {"the", 2}, {"katana", 3}}},
+		{"Tío Ñandú, ¿qué tal?", []Token{{"tio", 0}, {"nandu", 1}, {"que", 2}, {"tal", 3}}},
+		{"Café vs Café", []Token{{"cafe", 0}, {"vs", 1}, {"cafe", 2}}},
+		// One-letter words are not indexed but keep their positions
+		{"a b cd — e-f g_h 3.14 x2", []Token{{"cd", 2}, {"14", 8}, {"x2", 9}}},
+		{strings.Repeat("x", 40) + " " + strings.Repeat("y", 41) + " z end", []Token{{strings.Repeat("x", 40), 0}, {"end", 3}}},
+		{"日本語 東京", []Token{{"日本語", 0}, {"東京", 1}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShardName(t *testing.T) {
+	tests := map[string]string{
+		"saga":  "sa",
+		"x2":    "x2",
+		"14":    "14",
+		"ça":    "_c3a761",
+		"日本語":   "_e697a5e69cac",
+		"ab":    "ab",
+		"a_":    "_615f",
+		"cafe":  "ca",
+		"ωμεγα": "_cf89cebc",
+	}
+	for term, want := range tests {
+		if got := ShardName(term); got != want {
+			t.Errorf("ShardName(%q) = %q, want %q", term, got, want)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	ix := New()
+	ix.Add(Document{URL: "saga/v1-c1.html", Title: "Saga 1", Excerpt: "The duel", Text: "The duel. The saga!"})
+	ix.Add(Document{URL: "saga/v1-c2.html", Title: "Saga 2", Excerpt: "日本", Text: "日本 saga"})
+	if ix.Terms() != 4 {
+		t.Errorf("Terms() = %d, want 4", ix.Terms())
+	}
+	files, err := ix.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"index.json":         `{"version":1,"docs":[["saga/v1-c1.html","Saga 1","The duel"],["saga/v1-c2.html","Saga 2","日本"]],"shards":["_e697a5e69cac","du","sa","th"]}`,
+		"th.json":            `{"the":[[0,0,2]]}`,
+		"du.json":            `{"duel":[[0,1]]}`,
+		"sa.json":            `{"saga":[[0,3],[1,1]]}`,
+		"_e697a5e69cac.json": `{"日本":[[1,0]]}`,
+	}
+	if len(files) != len(want) {
+		t.Errorf("files = %v, want %d files", keys(files), len(want))
+	}
+	for name, data := range want {
+		if got := string(files[name]); got != data {
+			t.Errorf("%s = %s, want %s", name, got, data)
+		}
+	}
+}
+
+func TestFilesEmpty(t *testing.T) {
+	files, err := New().Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(files["index.json"]), `{"version":1,"docs":[],"shards":[]}`; got != want || len(files) != 1 {
+		t.Errorf("files = %v, index.json = %s, want only %s", keys(files), got, want)
+	}
+}
+
+// searchJS is the script of the search page, which must read the index the
+// way this package writes it.
+const searchJS = "../../themes/default/static/js/search.js"
+
+// jsFunctionRegex finds a function of search.js, indented by one level, with
+// its body.
+var jsFunctionRegex = regexp.MustCompile(`(?ms)^    function (fold|tokenize|shardName)\(.*?^    }$`)
+
+// TestScriptMatches runs the folding, tokenizing and shard naming of search.js
+// in Node.js, when it is installed, and compares them with this package's.
+func TestScriptMatches(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	src, err := os.ReadFile(searchJS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	constants := regexp.MustCompile(`(?m)^    var MIN_TERM.*$`).Find(src)
+	functions := jsFunctionRegex.FindAll(src, -1)
+	if constants == nil || len(functions) != 3 {
+		t.Fatalf("could not find the constants and the fold, tokenize and shardName functions in %s", searchJS)
+	}
+	input, _ := json.Marshal(tokenizeCases)
+	script := string(constants) + "\n" + string(functions[0]) + "\n" + string(functions[1]) + "\n" + string(functions[2]) + `
+var out = ` + string(input) + `.map(function (text) {
+    return { fold: fold(text), tokens: tokenize(text).map(function (t) { return [t.term, t.position, shardName(t.term)]; }) };
+});
+process.stdout.write(JSON.stringify(out));
+`
+	cmd := exec.Command(node, "-e", script)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("node failed: %v", err)
+	}
+	var results []struct {
+		Fold   string
+		Tokens [][]any
+	}
+	if err := json.Unmarshal(output, &results); err != nil {
+		t.Fatalf("could not read node's output %q: %v", output, err)
+	}
+
+	for i, text := range tokenizeCases {
+		if got, want := results[i].Fold, utils.Fold(text); got != want {
+			t.Errorf("%q: search.js folds to %q, Go to %q", text, got, want)
+		}
+		var want [][]any
+		for _, token := range Tokenize(text) {
+			want = append(want, []any{token.Term, float64(token.Position), ShardName(token.Term)})
+		}
+		if got := results[i].Tokens; len(got) > 0 || len(want) > 0 {
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%q: search.js tokens %v, Go tokens %v", text, got, want)
+			}
+		}
+	}
+}
+
+func keys(m map[string][]byte) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,152 @@
+// Package stats measures chapters and draws the bar charts of the statistics
+// page as inline SVG, so the page needs no script or chart library.
+package stats
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/templatefuncs"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Count measures one chapter's content.
+func Count(content template.HTML) models.Stats {
+	text := templatefuncs.PlainText(content)
+	chars := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			chars++
+		}
+	}
+	words := templatefuncs.WordCount(text)
+	return models.Stats{Chapters: 1, Words: words, Chars: chars, ReadingMinutes: templatefuncs.ReadingMinutes(words)}
+}
+
+// Sum adds up the stats of the chapters. The reading time is worked out from
+// the total words rather than added up, so it is not rounded once per chapter.
+func Sum(chapters []*models.Chapter) models.Stats {
+	var total models.Stats
+	for _, chapter := range chapters {
+		if chapter == nil {
+			continue
+		}
+		total = Add(total, chapter.Stats)
+	}
+	return total
+}
+
+// Add returns the totals of a and b.
+func Add(a, b models.Stats) models.Stats {
+	total := models.Stats{Chapters: a.Chapters + b.Chapters, Words: a.Words + b.Words, Chars: a.Chars + b.Chars}
+	if total.Chapters > 0 {
+		total.ReadingMinutes = templatefuncs.ReadingMinutes(total.Words)
+	}
+	return total
+}
+
+// Month is the number of chapters first published in one month.
+type Month struct {
+	Start    time.Time // First day of the month, UTC
+	Chapters int
+}
+
+// Cadence counts the chapters created in each month, from the month of the
+// first dated chapter to that of the last, including months without any.
+// Chapters without a creation date are left out; nil means none has one.
+func Cadence(chapters []*models.Chapter) []Month {
+	counts := make(map[time.Time]int)
+	var first, last time.Time
+	for _, chapter := range chapters {
+		if chapter == nil || chapter.CreatedAt.IsZero() {
+			continue
+		}
+		created := chapter.CreatedAt.UTC()
+		month := time.Date(created.Year(), created.Month(), 1, 0, 0, 0, 0, time.UTC)
+		counts[month]++
+		if first.IsZero() || month.Before(first) {
+			first = month
+		}
+		if month.After(last) {
+			last = month
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	var months []Month
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		months = append(months, Month{Start: month, Chapters: counts[month]})
+	}
+	return months
+}
+
+// Bar is one bar of a Chart.
+type Bar struct {
+	Value int
+	Label string // Shown as the bar's tooltip, e.g. "Vol. 1 Ch. 3: 2,345 words"
+	Group int    // Neighbouring groups (such as volumes) alternate colours
+}
+
+// Chart is a bar chart with a zero baseline.
+type Chart struct {
+	Title      string // Accessible name of the chart
+	Bars       []Bar
+	MaxLabel   string // Written by the top of the value axis, e.g. "4,210 words"
+	StartLabel string // Written under the first bar
+	EndLabel   string // Written under the last bar
+}
+
+// Dimensions of a chart, in SVG user units. The SVG scales to its container.
+const (
+	chartWidth   = 640
+	chartHeight  = 200
+	marginTop    = 16 // Room for MaxLabel
+	marginBottom = 20 // Room for StartLabel and EndLabel
+	maxBarWidth  = 48 // Few bars stay bars rather than blocks
+	fontSize     = 11
+)
+
+// groupColors are the bar colours of alternate groups.
+var groupColors = [2]string{"#485fc7", "#8c9fe6"}
+
+// SVG draws the chart, or returns "" when it has no bars.
+func (c Chart) SVG() template.HTML {
+	if len(c.Bars) == 0 {
+		return ""
+	}
+	maxValue := 0
+	for _, bar := range c.Bars {
+		maxValue = max(maxValue, bar.Value)
+	}
+	plotHeight := float64(chartHeight - marginTop - marginBottom)
+	baseline := float64(chartHeight - marginBottom)
+	slot := float64(chartWidth) / float64(len(c.Bars))
+	width := slot
+	if slot >= 4 {
+		width = min(slot-max(1, slot/8), maxBarWidth)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="stats-chart" viewBox="0 0 %d %d" role="img" aria-label="%s" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight, html.EscapeString(c.Title))
+	fmt.Fprintf(&b, "\n<title>%s</title>\n", html.EscapeString(c.Title))
+	fmt.Fprintf(&b, `<g font-size="%d" fill="#4a4a4a">`, fontSize)
+	fmt.Fprintf(&b, `<text x="0" y="%d">%s</text>`, fontSize, html.EscapeString(c.MaxLabel))
+	fmt.Fprintf(&b, `<text x="0" y="%d">%s</text>`, chartHeight-4, html.EscapeString(c.StartLabel))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, chartWidth, chartHeight-4, html.EscapeString(c.EndLabel))
+	b.WriteString("</g>\n")
+	fmt.Fprintf(&b, `<line x1="0" y1="%.1f" x2="%d" y2="%.1f" stroke="#dbdbdb" />`+"\n", baseline, chartWidth, baseline)
+	for i, bar := range c.Bars {
+		height := 0.0
+		if maxValue > 0 {
+			height = plotHeight * float64(bar.Value) / float64(maxValue)
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`+"\n",
+			float64(i)*slot+(slot-width)/2, baseline-height, width, height, groupColors[bar.Group%2], html.EscapeString(bar.Label))
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String()) // Every text in it is escaped above
+}
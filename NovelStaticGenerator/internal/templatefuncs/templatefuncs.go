@@ -0,0 +1,166 @@
+package templatefuncs
+
+import (
+	"NovelStaticGenerator/internal/assets"
+	"NovelStaticGenerator/internal/i18n"
+	"NovelStaticGenerator/internal/markdown"
+	"NovelStaticGenerator/internal/urls"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordsPerMinute is the reading speed used by readingTime.
+const wordsPerMinute = 200
+
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// New returns the full template function library bound to the page written at
+// pagePath in lang. The URL and asset helpers depend on the page and T on its
+// language; everything else is static.
+func New(b *urls.Builder, c *i18n.Catalog, m *assets.Manifest, pagePath, lang string) template.FuncMap {
+	funcs := template.FuncMap{
+		"wordCount":   WordCount,
+		"readingTime": ReadingTime,
+		"formatDate":  FormatDate,
+		"truncate":    Truncate,
+		"markdownify": Markdownify,
+		"plural":      Plural,
+		"dict":        Dict,
+		"slice":       Slice,
+	}
+	for name, fn := range b.FuncMap(pagePath) {
+		funcs[name] = fn
+	}
+	for name, fn := range c.FuncMap(lang) {
+		funcs[name] = fn
+	}
+	for name, fn := range m.FuncMap(b, pagePath) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// PlainText converts a string or template.HTML into plain text by dropping tags
+// and decoding entities.
+func PlainText(v any) string {
+	switch s := v.(type) {
+	case template.HTML:
+		return html.UnescapeString(tagRegex.ReplaceAllString(string(s), " "))
+	case string:
+		return s
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+// WordCount counts the words in a string or template.HTML (tags are ignored).
+func WordCount(v any) int {
+	count := 0
+	for _, field := range strings.Fields(PlainText(v)) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// ReadingTime estimates the minutes needed to read v, never less than one.
+func ReadingTime(v any) int {
+	return ReadingMinutes(WordCount(v))
+}
+
+// ReadingMinutes estimates the minutes needed to read the given number of
+// words, never less than one.
+func ReadingMinutes(words int) int {
+	minutes := int(math.Ceil(float64(words) / wordsPerMinute))
+	if minutes < 1 {
+		return 1
+	}
+	return minutes
+}
+
+// monthNames holds month names for the languages formatDate knows about.
+var monthNames = map[string][12]string{
+	"en": {"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	"es": {"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+}
+
+// FormatDate formats t as a long date in the given language ("en", "es", or a
+// tag such as "es-MX"); unknown languages fall back to English. It takes the
+// language first so it can be used in pipelines: {{ .CreatedAt | formatDate "es" }}.
+// A zero time formats as "".
+func FormatDate(lang string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	base := strings.ToLower(strings.SplitN(strings.ReplaceAll(lang, "_", "-"), "-", 2)[0])
+	months, ok := monthNames[base]
+	if !ok {
+		base, months = "en", monthNames["en"]
+	}
+	month := months[t.Month()-1]
+	if base == "es" {
+		return fmt.Sprintf("%d de %s de %d", t.Day(), month, t.Year())
+	}
+	return fmt.Sprintf("%s %d, %d", month, t.Day(), t.Year())
+}
+
+// Truncate shortens s to at most limit characters, cutting at a word boundary
+// when possible and appending an ellipsis. Used as {{ .Description | truncate 140 }}.
+func Truncate(limit int, v any) string {
+	s := strings.TrimSpace(PlainText(v))
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	cut := string([]rune(s)[:limit])
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > limit/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) + "…"
+}
+
+// Markdownify renders Markdown to HTML. Raw HTML in the input is escaped.
+func Markdownify(v any) template.HTML {
+	return template.HTML(markdown.Render(PlainText(v)))
+}
+
+// Plural returns singular when n is 1 and plural otherwise:
+// {{ len .Chapters }} {{ plural (len .Chapters) "chapter" "chapters" }}.
+func Plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+// Dict builds a map from alternating keys and values, mainly to pass several
+// values to a partial: {{ template "card" dict "Novel" . "Compact" true }}.
+func Dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict needs an even number of arguments")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// Slice returns its arguments as a slice: {{ range slice "a" "b" }}.
+func Slice(items ...any) []any {
+	return items
+}
@@ -0,0 +1,189 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Directory layout of a theme. Every directory is optional in a user theme;
+// anything missing falls back to the built-in default theme.
+const (
+	LayoutsDir  = "layouts"  // Page templates; _base.html is the shared layout
+	PartialsDir = "partials" // Templates parsed into every page template set (as are layouts/_*.html)
+	StaticDir   = "static"   // Assets copied verbatim into the output directory
+	BaseLayout  = "_base.html"
+
+	// NovelsDir holds per-novel overrides under layouts/: layouts/novels/<slug>/chapter.html
+	// replaces chapter.html for that novel only.
+	NovelsDir = "novels"
+)
+
+// NovelOverride returns the template name that overrides page (e.g. "chapter")
+// for the novel with the given slug.
+func NovelOverride(slug, page string) string {
+	return path.Join(NovelsDir, slug, page)
+}
+
+// OverrideSlug reports the novel slug a template name overrides, if any.
+func OverrideSlug(name string) (string, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) == 3 && parts[0] == NovelsDir {
+		return parts[1], true
+	}
+	return "", false
+}
+
+// Theme is a user theme directory layered over the built-in default theme.
+type Theme struct {
+	Dir string // User theme directory ("" when only the built-in theme is used)
+	fs  fs.FS
+}
+
+// Load returns the theme found in dir layered over builtin. An empty dir selects
+// the built-in theme alone.
+func Load(dir string, builtin fs.FS) (*Theme, error) {
+	if dir == "" {
+		return &Theme{fs: builtin}, nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not open theme directory '%s': %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("theme '%s' is not a directory", dir)
+	}
+	return &Theme{Dir: dir, fs: &overlayFS{upper: os.DirFS(dir), lower: builtin}}, nil
+}
+
+// FS exposes the merged theme files.
+func (t *Theme) FS() fs.FS {
+	return t.fs
+}
+
+// Static returns the merged static asset tree.
+func (t *Theme) Static() (fs.FS, error) {
+	return fs.Sub(t.fs, StaticDir)
+}
+
+// Exists reports whether a theme file (slash-separated, relative to the theme root) exists.
+func (t *Theme) Exists(name string) bool {
+	_, err := fs.Stat(t.fs, name)
+	return err == nil
+}
+
+// ReadFile reads a theme file (slash-separated, relative to the theme root).
+func (t *Theme) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(t.fs, name)
+}
+
+// Layouts lists the page templates under layouts/, relative to that directory
+// and sorted. Files starting with "_" are shared layouts, not pages.
+func (t *Theme) Layouts() ([]string, error) {
+	return t.list(LayoutsDir, func(name string) bool {
+		return !strings.HasPrefix(path.Base(name), "_")
+	})
+}
+
+// Partials lists the templates loaded into every page set, relative to the theme
+// root and sorted: everything under partials/ plus the _*.html files directly
+// in layouts/ (except the base layout itself).
+func (t *Theme) Partials() ([]string, error) {
+	names, err := t.list(PartialsDir, func(string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = path.Join(PartialsDir, name)
+	}
+	layoutPartials, err := t.LayoutPartials(".")
+	if err != nil {
+		return nil, err
+	}
+	return append(names, layoutPartials...), nil
+}
+
+// LayoutPartials lists the _*.html files directly inside layouts/<dir>, except
+// _base.html, relative to the theme root and sorted. Pages in a per-novel
+// override directory get that directory's partials on top of the shared ones.
+func (t *Theme) LayoutPartials(dir string) ([]string, error) {
+	names, err := t.list(path.Join(LayoutsDir, dir), func(name string) bool {
+		return !strings.Contains(name, "/") && strings.HasPrefix(name, "_") && name != BaseLayout
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = path.Join(LayoutsDir, dir, name)
+	}
+	return names, nil
+}
+
+// list walks dir and returns the .html files (relative to dir) accepted by keep.
+func (t *Theme) list(dir string, keep func(name string) bool) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(t.fs, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".html" {
+			return nil
+		}
+		name := strings.TrimPrefix(p, dir+"/")
+		if keep(name) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list theme directory '%s': %w", dir, err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// overlayFS serves files from upper when present and from lower otherwise.
+// Directory listings are merged so discovery sees files from both layers.
+type overlayFS struct {
+	upper, lower fs.FS
+}
+
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.lower.Open(name)
+}
+
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := fs.ReadDir(o.upper, name)
+	lower, lowerErr := fs.ReadDir(o.lower, name)
+	if upperErr != nil && lowerErr != nil {
+		return nil, upperErr
+	}
+
+	merged := make(map[string]fs.DirEntry, len(upper)+len(lower))
+	for _, e := range lower {
+		merged[e.Name()] = e
+	}
+	for _, e := range upper {
+		merged[e.Name()] = e
+	}
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, e := range merged {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
@@ -0,0 +1,396 @@
+// Package typography cleans up the punctuation of chapter text from machine
+// and fan translations: straight quotes become the curly quotes or guillemets
+// of the novel's language, "--" an em dash, "..." an ellipsis, and the spaces
+// around ? ! : ; and quotes follow the language's rules. Only text is changed,
+// never markup, attribute values or the content of code, pre and the like.
+//
+// Quotes are opened or closed by what comes before them, as in SmartyPants: a
+// quote at the start of a paragraph or line, after a space or an opening
+// bracket opens, any other closes. Inline tags such as <em> do not interrupt
+// this, so `"<em>Go</em>"` gets both of its quotes right.
+package typography
+
+import (
+	"NovelStaticGenerator/internal/htmltoken"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rules are the changes a Transformer makes. The zero value makes none.
+type Rules struct {
+	NFC      bool // Compose characters to Unicode NFC ("e" and a combining accent become "é")
+	Quotes   bool // Straight quotes become the language's quotes, apostrophes ’
+	Dashes   bool // "--" and "---" become —, a spaced hyphen the language's dash
+	Ellipses bool // "..." and ". . ." become …
+	Spacing  bool // Spaces before ? ! : ; and inside guillemets follow the language
+}
+
+// ruleNames are the names ParseRules accepts, in the order String writes them.
+var ruleNames = []string{"nfc", "quotes", "dashes", "ellipses", "spacing"}
+
+// ParseRules reads a comma-separated list of rule names, such as
+// "quotes,dashes". "all" turns every rule on; "none" or "" turns them off.
+func ParseRules(s string) (Rules, error) {
+	var rules Rules
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "", "none":
+		case "all":
+			rules = Rules{NFC: true, Quotes: true, Dashes: true, Ellipses: true, Spacing: true}
+		case "nfc":
+			rules.NFC = true
+		case "quotes":
+			rules.Quotes = true
+		case "dashes":
+			rules.Dashes = true
+		case "ellipses":
+			rules.Ellipses = true
+		case "spacing":
+			rules.Spacing = true
+		default:
+			return Rules{}, fmt.Errorf("unknown typography rule %q (known: all, none, %s)", strings.TrimSpace(name), strings.Join(ruleNames, ", "))
+		}
+	}
+	return rules, nil
+}
+
+// Any reports whether any rule is on.
+func (r Rules) Any() bool {
+	return r != Rules{}
+}
+
+func (r Rules) String() string {
+	on := []bool{r.NFC, r.Quotes, r.Dashes, r.Ellipses, r.Spacing}
+	var names []string
+	for i, name := range ruleNames {
+		if on[i] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// Non-breaking spaces.
+const (
+	nbsp       = "\u00a0"
+	narrowNbsp = "\u202f"
+)
+
+// Locale holds the typographic conventions of a language.
+type Locale struct {
+	Quotes       [4]string       // Opening and closing double quotes, then single; "" leaves quotes straight
+	SpacedDash   string          // Replaces a hyphen between spaces; "" leaves it
+	DialogueDash bool            // A hyphen starting a paragraph or line opens dialogue: "- Hola" becomes "—Hola"
+	SpaceBefore  map[rune]string // The space wanted before each mark: "" for none, or a non-breaking space
+	NoSpaceAfter string          // Marks never followed by a space, like ¿ and ¡
+	QuoteSpace   string          // Space inside guillemets, "" for none
+}
+
+// Locales are the conventions of the languages the transformer knows, by
+// primary language subtag. Other languages get dashes, ellipses and NFC but
+// keep their quotes and spacing.
+var Locales = map[string]Locale{
+	"en": {
+		Quotes:      [4]string{"“", "”", "‘", "’"},
+		SpacedDash:  "–",
+		SpaceBefore: map[rune]string{'?': "", '!': ""},
+	},
+	"es": {
+		Quotes:       [4]string{"«", "»", "“", "”"},
+		SpacedDash:   "—",
+		DialogueDash: true,
+		SpaceBefore:  map[rune]string{'?': "", '!': ""},
+		NoSpaceAfter: "¿¡",
+	},
+	"fr": {
+		Quotes:       [4]string{"«", "»", "“", "”"},
+		SpacedDash:   "–",
+		DialogueDash: true,
+		SpaceBefore:  map[rune]string{'?': narrowNbsp, '!': narrowNbsp, ';': narrowNbsp, ':': nbsp},
+		QuoteSpace:   narrowNbsp,
+	},
+	"de": {
+		Quotes:      [4]string{"„", "“", "‚", "‘"},
+		SpacedDash:  "–",
+		SpaceBefore: map[rune]string{'?': "", '!': ""},
+	},
+}
+
+// LocaleFor returns the conventions of lang, a BCP 47 tag such as "es-MX".
+func LocaleFor(lang string) Locale {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	return Locales[base]
+}
+
+// skipElements hold text that is not prose: code, and what is typed or shown
+// as is.
+var skipElements = map[string]bool{
+	"code": true, "pre": true, "kbd": true, "samp": true, "tt": true, "textarea": true,
+	"script": true, "style": true,
+}
+
+// inlineElements continue the text around them. Any other tag starts or ends
+// a block, and so does <br>, which starts a line.
+var inlineElements = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "cite": true, "data": true, "del": true,
+	"dfn": true, "em": true, "font": true, "i": true, "ins": true, "label": true, "mark": true, "q": true,
+	"ruby": true, "rp": true, "rt": true, "s": true, "small": true, "span": true, "strong": true,
+	"sub": true, "sup": true, "time": true, "u": true, "var": true, "wbr": true,
+}
+
+var (
+	spacedEllipsisRegex = regexp.MustCompile(`\. \. \.`)
+	spacedHyphenRegex   = regexp.MustCompile(`(\S) - (\S)`)
+)
+
+// Transformer applies rules to HTML in the conventions of one language.
+type Transformer struct {
+	rules  Rules
+	locale Locale
+}
+
+// New returns a Transformer applying rules for lang.
+func New(rules Rules, lang string) *Transformer {
+	return &Transformer{rules: rules, locale: LocaleFor(lang)}
+}
+
+// Apply returns content with the rules applied to its text.
+func (t *Transformer) Apply(content template.HTML) template.HTML {
+	if !t.rules.Any() || content == "" {
+		return content
+	}
+	var b strings.Builder
+	skip := 0       // Depth of skipped elements around the current token
+	prev := rune(0) // Last character of text written in the current block, 0 at its start
+	z := htmltoken.New(string(content))
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			break
+		}
+		switch tok.Type {
+		case htmltoken.Text:
+			if skip > 0 {
+				break
+			}
+			text := html.UnescapeString(tok.Data)
+			if out := t.text(text, &prev); out != text {
+				b.WriteString(escapeText(out))
+				continue
+			}
+		case htmltoken.StartTag, htmltoken.EndTag:
+			if skipElements[tok.Data] {
+				if tok.Type == htmltoken.StartTag && !tok.SelfClosing {
+					skip++
+				} else if tok.Type == htmltoken.EndTag && skip > 0 {
+					skip--
+				}
+				prev = 'a' // Code reads as a word to the text around it
+			} else if !inlineElements[tok.Data] {
+				prev = 0
+			}
+		}
+		b.WriteString(tok.Raw)
+	}
+	return template.HTML(b.String()) // Tags kept as they were; changed text re-escaped
+}
+
+// Text returns s, plain text such as a heading title, with the rules applied
+// as to a block of its own.
+func (t *Transformer) Text(s string) string {
+	if !t.rules.Any() {
+		return s
+	}
+	prev := rune(0)
+	return t.text(s, &prev)
+}
+
+// text applies the rules to one text node. prev is the last character before
+// it in the same block, and is updated to the last one written.
+func (t *Transformer) text(s string, prev *rune) string {
+	if t.rules.NFC {
+		s = norm.NFC.String(s)
+	}
+	if t.rules.Ellipses {
+		s = strings.ReplaceAll(s, "...", "…")
+		s = spacedEllipsisRegex.ReplaceAllString(s, "…")
+	}
+	if t.rules.Dashes {
+		s = strings.ReplaceAll(s, "---", "—")
+		s = strings.ReplaceAll(s, "--", "—")
+		if t.locale.SpacedDash != "" {
+			// Twice, since each match takes the character after the hyphen
+			for range 2 {
+				s = spacedHyphenRegex.ReplaceAllString(s, "$1 "+t.locale.SpacedDash+" $2")
+			}
+		}
+	}
+	if !t.rules.Quotes && !t.rules.Spacing && !(t.rules.Dashes && t.locale.DialogueDash) {
+		if s != "" {
+			*prev = lastRune(s, *prev)
+		}
+		return s
+	}
+
+	in := []rune(s)
+	out := make([]rune, 0, len(in)+8)
+	last := func() rune {
+		if len(out) > 0 {
+			return out[len(out)-1]
+		}
+		return *prev
+	}
+	for i := 0; i < len(in); i++ {
+		r := in[i]
+		next := rune(0)
+		if i+1 < len(in) {
+			next = in[i+1]
+		}
+		switch {
+		case r == '-' && t.rules.Dashes && t.locale.DialogueDash && lineStart(out, *prev) && (next == ' ' || unicode.IsLetter(next) || next == '¿' || next == '¡'):
+			out = append(out, '—')
+			for i+1 < len(in) && in[i+1] == ' ' {
+				i++
+			}
+			continue
+
+		case (r == '"' || r == '\'') && t.rules.Quotes:
+			if r == '\'' && unicode.IsLetter(last()) && unicode.IsLetter(next) {
+				out = append(out, '’') // An apostrophe, as in "don't" or "l'homme"
+				continue
+			}
+			if t.locale.Quotes[0] == "" {
+				break
+			}
+			quotes := t.locale.Quotes[0:2]
+			if r == '\'' {
+				quotes = t.locale.Quotes[2:4]
+			}
+			if opens(last()) {
+				out = t.openQuote(out, quotes[0])
+				for t.rules.Spacing && t.locale.QuoteSpace != "" && quotes[0] == "«" && i+1 < len(in) && isSpace(in[i+1]) {
+					i++
+				}
+			} else {
+				out = t.closeQuote(out, quotes[1], *prev)
+			}
+			continue
+
+		case r == '«' && t.rules.Spacing:
+			out = t.openQuote(out, "«")
+			for t.locale.QuoteSpace != "" && i+1 < len(in) && isSpace(in[i+1]) {
+				i++
+			}
+			continue
+
+		case r == '»' && t.rules.Spacing:
+			out = t.closeQuote(out, "»", *prev)
+			continue
+
+		case t.rules.Spacing && hasSpaceRule(t.locale.SpaceBefore, r):
+			space := t.locale.SpaceBefore[r]
+			out = trimSpaces(out)
+			// Only before a mark ending a word, so "http://" and "10:30" keep theirs
+			if space != "" && wordEnd(last()) && (next == 0 || isSpace(next) || strings.ContainsRune("?!»”)…", next)) {
+				out = append(out, []rune(space)...)
+			}
+			out = append(out, r)
+			continue
+
+		case r == ' ' && t.rules.Spacing && strings.ContainsRune(t.locale.NoSpaceAfter, last()) && last() != 0:
+			continue
+		}
+		out = append(out, r)
+	}
+	if len(out) > 0 {
+		*prev = out[len(out)-1]
+	}
+	return string(out)
+}
+
+// openQuote writes an opening quote, with the locale's space after guillemets.
+func (t *Transformer) openQuote(out []rune, quote string) []rune {
+	out = append(out, []rune(quote)...)
+	if t.rules.Spacing && quote == "«" && t.locale.QuoteSpace != "" {
+		out = append(out, []rune(t.locale.QuoteSpace)...)
+	}
+	return out
+}
+
+// closeQuote writes a closing quote, with the locale's space before
+// guillemets.
+func (t *Transformer) closeQuote(out []rune, quote string, prev rune) []rune {
+	if t.rules.Spacing && quote == "»" && t.locale.QuoteSpace != "" {
+		out = trimSpaces(out)
+		if len(out) > 0 || prev != 0 {
+			out = append(out, []rune(t.locale.QuoteSpace)...)
+		}
+	}
+	return append(out, []rune(quote)...)
+}
+
+// opens reports whether a quote after r opens: at the start of a block, after
+// a space, a dash or an opening bracket or quote.
+func opens(r rune) bool {
+	return r == 0 || isSpace(r) || strings.ContainsRune("([{¿¡—–-/«“‘„‚", r)
+}
+
+// wordEnd reports whether r can end the word before a mark such as "?".
+func wordEnd(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || strings.ContainsRune(")]}»”’…", r)
+}
+
+// lineStart reports whether out, after prev, is at the start of a paragraph
+// or line, leaving aside spaces.
+func lineStart(out []rune, prev rune) bool {
+	for _, r := range out {
+		if !isSpace(r) {
+			return false
+		}
+	}
+	return prev == 0
+}
+
+// trimSpaces drops the spaces at the end of out.
+func trimSpaces(out []rune) []rune {
+	for len(out) > 0 && isSpace(out[len(out)-1]) {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// hasSpaceRule reports whether rules say what space goes before r.
+func hasSpaceRule(rules map[rune]string, r rune) bool {
+	_, ok := rules[r]
+	return ok
+}
+
+func isSpace(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+// lastRune returns the last rune of s, or def when s is empty.
+func lastRune(s string, def rune) rune {
+	r := []rune(s)
+	if len(r) == 0 {
+		return def
+	}
+	return r[len(r)-1]
+}
+
+// escapeText escapes text for HTML. Unlike html.EscapeString it leaves quotes
+// alone, which need no escaping outside attributes.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
@@ -0,0 +1,135 @@
+package typography
+
+import (
+	"html/template"
+	"testing"
+)
+
+const (
+	nb  = "\u00a0" // nbsp
+	nnb = "\u202f" // narrowNbsp
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		lang, in, want string
+	}{
+		// English
+		{"en", `<p>"Hello," she said. 'Hi.'</p>`, `<p>“Hello,” she said. ‘Hi.’</p>`},
+		{"en", `<p>Don't -- wait... no --- now . . . go</p>`, `<p>Don’t — wait… no — now … go</p>`},
+		{"en", `<p>pages 3 - 5</p>`, `<p>pages 3 – 5</p>`},
+		{"en", `<p>Why ? Stop !</p>`, `<p>Why? Stop!</p>`},
+		{"en-GB", `<p>"Yes"</p>`, `<p>“Yes”</p>`},
+
+		// Spanish
+		{"es", `<p>"Hola", dijo.</p>`, `<p>«Hola», dijo.</p>`},
+		{"es", `<p>- Hola - dijo él.</p>`, `<p>—Hola — dijo él.</p>`},
+		{"es", `<p>¿ Qué ? ¡ Ya !</p>`, `<p>¿Qué? ¡Ya!</p>`},
+		{"es-MX", `<p>Él dijo 'sí'...</p>`, `<p>Él dijo “sí”…</p>`},
+
+		// French
+		{"fr", `<p>"Bonjour", dit-il.</p>`, `<p>«` + nnb + `Bonjour` + nnb + `», dit-il.</p>`},
+		{"fr", `<p>« Oui »</p>`, `<p>«` + nnb + `Oui` + nnb + `»</p>`},
+		{"fr", `<p>Quoi? Non! Alors; voilà: fini</p>`, `<p>Quoi` + nnb + `? Non` + nnb + `! Alors` + nnb + `; voilà` + nb + `: fini</p>`},
+		{"fr", `<p>Quoi ?</p>`, `<p>Quoi` + nnb + `?</p>`},
+		{"fr", `<p>À 10:30, voir https://example.com</p>`, `<p>À 10:30, voir https://example.com</p>`},
+		{"fr", `<p>- Oui, l'homme.</p>`, `<p>—Oui, l’homme.</p>`},
+
+		// German
+		{"de", `<p>"Ja", sagte er. 'Nein'</p>`, `<p>„Ja“, sagte er. ‚Nein‘</p>`},
+		{"de", `<p>Wirklich ?</p>`, `<p>Wirklich?</p>`},
+
+		// Languages without a locale keep their quotes and spacing
+		{"ja", `<p>"Yes" -- no... ?</p>`, `<p>"Yes" — no… ?</p>`},
+		{"", `<p>'a' - b</p>`, `<p>'a' - b</p>`},
+
+		// Unicode NFC
+		{"en", "<p>Cafe\u0301</p>", "<p>Caf\u00e9</p>"},
+
+		// Quotes across inline tags, but not across blocks
+		{"en", `<p>"<em>Go</em>"</p>`, `<p>“<em>Go</em>”</p>`},
+		{"en", `<p>a</p><p>"b"</p>`, `<p>a</p><p>“b”</p>`},
+		{"en", `<p>x<br>"y"</p>`, `<p>x<br>“y”</p>`},
+
+		// Markup, code and attribute values are never touched
+		{"en", `<p title="a &quot;b&quot; -- c...">"d"</p>`, `<p title="a &quot;b&quot; -- c...">“d”</p>`},
+		{"en", `<a href="/x--y...z" data-q='"'>"link"</a>`, `<a href="/x--y...z" data-q='"'>“link”</a>`},
+		{"en", `<p>Run <code>a -- "b"...</code> now</p>`, `<p>Run <code>a -- "b"...</code> now</p>`},
+		{"en", "<pre>\"a\" -- b...\n<b>'c'</b></pre><p>\"d\"</p>", "<pre>\"a\" -- b...\n<b>'c'</b></pre><p>“d”</p>"},
+		{"en", `<kbd>--</kbd><samp>...</samp><tt>"x"</tt>`, `<kbd>--</kbd><samp>...</samp><tt>"x"</tt>`},
+		{"en", `<script>var s = "a--b...";</script><style>a::before{content:"--"}</style>`, `<script>var s = "a--b...";</script><style>a::before{content:"--"}</style>`},
+		{"en", `<!-- "x" -- y... -->`, `<!-- "x" -- y... -->`},
+		{"fr", `<p>Voir <a href="http://a.fr/b?c=d:e">ici</a> : oui</p>`, `<p>Voir <a href="http://a.fr/b?c=d:e">ici</a>` + nb + `: oui</p>`},
+
+		// Text is re-escaped where it changed
+		{"en", `<p>"a &amp; b" &lt;c&gt;</p>`, `<p>“a &amp; b” &lt;c&gt;</p>`},
+		{"en", `<p>a &amp; b</p>`, `<p>a &amp; b</p>`},
+	}
+	rules, err := ParseRules("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		got := New(rules, tt.lang).Apply(template.HTML(tt.in))
+		if string(got) != tt.want {
+			t.Errorf("[%s] Apply(%q)\n got %q\nwant %q", tt.lang, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	in := template.HTML(`<p>"a" -- b... c ?</p>`)
+	tests := []struct {
+		rules, want string
+	}{
+		{"none", `<p>"a" -- b... c ?</p>`},
+		{"quotes", `<p>“a” -- b... c ?</p>`},
+		{"dashes", `<p>"a" — b... c ?</p>`},
+		{"ellipses", `<p>"a" -- b… c ?</p>`},
+		{"spacing", `<p>"a" -- b... c?</p>`},
+		{"quotes,dashes", `<p>“a” — b... c ?</p>`},
+	}
+	for _, tt := range tests {
+		rules, err := ParseRules(tt.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := New(rules, "en").Apply(in); string(got) != tt.want {
+			t.Errorf("rules %s: got %q, want %q", tt.rules, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	rules, _ := ParseRules("all")
+	if got, want := New(rules, "fr").Text(`"Duel" -- fin`), "«"+nnb+"Duel"+nnb+"» — fin"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+	if got, want := New(rules, "en").Text(`a < b & "c"`), `a < b & “c”`; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rules
+	}{
+		{"", Rules{}},
+		{"none", Rules{}},
+		{"all", Rules{NFC: true, Quotes: true, Dashes: true, Ellipses: true, Spacing: true}},
+		{" Quotes , dashes", Rules{Quotes: true, Dashes: true}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRules(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRules(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseRules("quotes,smart"); err == nil {
+		t.Error(`ParseRules("quotes,smart") did not fail`)
+	}
+	if got := (Rules{Quotes: true, Spacing: true}).String(); got != "quotes,spacing" {
+		t.Errorf("String() = %q, want quotes,spacing", got)
+	}
+}
@@ -0,0 +1,319 @@
+package urls
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/utils"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultPermalink reproduces the historical flat layout: <novel>/v1-c2.html and <novel>/v1-c2-styled.html.
+const DefaultPermalink = "{novel}/v{volume}-c{chapter}{variant}.html"
+
+// placeholderRegex matches a {name} placeholder inside a permalink pattern.
+var placeholderRegex = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// knownPlaceholders lists every placeholder a permalink pattern may use.
+var knownPlaceholders = map[string]bool{
+	"novel":      true, // slug of the novel name
+	"volume":     true, // volume number
+	"chapter":    true, // chapter number within the volume
+	"chapter_id": true, // database ID of the chapter
+	"title_slug": true, // slug of the chapter title, see AssignTitleSlugs
+	"variant":    true, // variant filename suffix, empty for the plain version
+}
+
+// Builder turns novels, volumes and chapters into output paths and links.
+// Every URL written into a page should come from here so the layout can be
+// changed in one place.
+type Builder struct {
+	Permalink  string   // Pattern for chapter pages, see knownPlaceholders
+	PrettyURLs bool     // Link to directories instead of their index.html
+	BaseURL    *url.URL // Public URL of the site root (nil if unknown)
+}
+
+// NewBuilder validates the permalink pattern and base URL and returns a Builder
+// using them. An empty pattern selects DefaultPermalink; an empty base URL leaves
+// absolute URLs (canonical links, feeds, sitemaps) disabled.
+func NewBuilder(permalink string, prettyURLs bool, baseURL string) (*Builder, error) {
+	if permalink == "" {
+		permalink = DefaultPermalink
+	}
+	if strings.HasPrefix(permalink, "/") || strings.HasPrefix(path.Clean(permalink), "..") {
+		return nil, fmt.Errorf("permalink %q must be relative to the site root", permalink)
+	}
+	for _, m := range placeholderRegex.FindAllStringSubmatch(permalink, -1) {
+		if !knownPlaceholders[m[1]] {
+			return nil, fmt.Errorf("permalink %q uses unknown placeholder {%s}", permalink, m[1])
+		}
+	}
+	if !strings.Contains(permalink, "{chapter}") && !strings.Contains(permalink, "{chapter_id}") && !strings.Contains(permalink, "{title_slug}") {
+		return nil, fmt.Errorf("permalink %q must contain {chapter}, {chapter_id} or {title_slug}", permalink)
+	}
+
+	b := &Builder{Permalink: permalink, PrettyURLs: prettyURLs}
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("base URL %q must be absolute, e.g. https://example.org/novels/", baseURL)
+		}
+		// Treat the base as a directory so "novels" and "novels/" resolve the same way
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		b.BaseURL = u
+	}
+	return b, nil
+}
+
+// IndexPath is the output path of the site's main index page.
+func (b *Builder) IndexPath() string {
+	return "index.html"
+}
+
+// NovelPath is the output path of a novel's landing page.
+func (b *Builder) NovelPath(novel *models.Novel) string {
+	return path.Join(novel.Slug, "index.html")
+}
+
+// VolumePath is the output path of a volume's index page.
+func (b *Builder) VolumePath(novel *models.Novel, volume *models.Volume) string {
+	return path.Join(novel.Slug, fmt.Sprintf("v%d", volume.Number), "index.html")
+}
+
+// PagePath is the output path of a content page: <slug>/index.html.
+func (b *Builder) PagePath(page *models.Page) string {
+	return path.Join(page.Slug, "index.html")
+}
+
+// CharacterPath is the output path of a character's glossary page.
+func (b *Builder) CharacterPath(character *models.Character) string {
+	return path.Join("characters", character.Slug+".html")
+}
+
+// CharactersPath is the output path of the character glossary index.
+func (b *Builder) CharactersPath() string {
+	return path.Join("characters", "index.html")
+}
+
+// StatsPath is the output path of the statistics page.
+func (b *Builder) StatsPath() string {
+	return "stats.html"
+}
+
+// SearchPath is the output path of the search page.
+func (b *Builder) SearchPath() string {
+	return "search.html"
+}
+
+// SearchIndexPath is the output path of a file of the search index, e.g.
+// "index.json" or a shard such as "sa.json".
+func (b *Builder) SearchIndexPath(file string) string {
+	return path.Join("search", file)
+}
+
+// ChapterPath expands the permalink pattern for one chapter. suffix is the
+// variant's filename suffix (e.g. "" for plain, "-styled" for Bulma). When the
+// pattern has no {variant} placeholder the suffix is added before the file extension, or to the
+// last directory for extension-less patterns such as "{novel}/{title_slug}".
+// Extension-less patterns are written as <dir>/index.html.
+func (b *Builder) ChapterPath(chapter *models.Chapter, suffix string) string {
+	pattern := b.Permalink
+	hasVariant := strings.Contains(pattern, "{variant}")
+	p := placeholderRegex.ReplaceAllStringFunc(pattern, func(m string) string {
+		switch m[1 : len(m)-1] {
+		case "novel":
+			return chapter.NovelSlug
+		case "volume":
+			return strconv.Itoa(chapter.VolumeNumber)
+		case "chapter":
+			return strconv.Itoa(chapter.ChapterNumber)
+		case "chapter_id":
+			return strconv.Itoa(chapter.ID)
+		case "title_slug":
+			if chapter.TitleSlug == "" {
+				slug, _ := titleSlug(chapter)
+				return slug
+			}
+			return chapter.TitleSlug
+		case "variant":
+			return suffix
+		}
+		return m
+	})
+
+	dirStyle := strings.HasSuffix(p, "/") || path.Ext(p) == ""
+	p = strings.TrimSuffix(path.Clean(p), "/")
+	if !hasVariant && suffix != "" {
+		if ext := path.Ext(p); !dirStyle {
+			p = strings.TrimSuffix(p, ext) + suffix + ext
+		} else {
+			p += suffix
+		}
+	}
+	if dirStyle {
+		p = path.Join(p, "index.html")
+	}
+	return p
+}
+
+// AssignTitleSlugs sets the TitleSlug of chapters, in reading order, so that
+// no two of them expand the permalink pattern to the same path. A chapter's
+// slug is the slug of its title, or chapter-<number> when the title is empty
+// or has nothing a slug can keep (such as a title in Japanese). A chapter
+// whose path would repeat one taken by an earlier chapter gets -v<volume>-c<number>
+// added, as a second "Interlude" would: "interlude-v2-c7", or "chapter-v2-c7"
+// instead of "chapter-7". Earlier chapters keep their slugs, so adding
+// chapters never moves the ones already published. taken holds the paths of
+// the chapters given before, across calls.
+func (b *Builder) AssignTitleSlugs(chapters []*models.Chapter, taken map[string]bool) {
+	if !strings.Contains(b.Permalink, "{title_slug}") {
+		return
+	}
+	for _, chapter := range chapters {
+		slug, numbered := titleSlug(chapter)
+		chapter.TitleSlug = slug
+		if taken[b.ChapterPath(chapter, "")] {
+			if numbered {
+				slug = "chapter"
+			}
+			chapter.TitleSlug = fmt.Sprintf("%s-v%d-c%d", slug, chapter.VolumeNumber, chapter.ChapterNumber)
+			if taken[b.ChapterPath(chapter, "")] {
+				// Only if another chapter is titled like this one's fallback
+				chapter.TitleSlug += "-" + strconv.Itoa(chapter.ID)
+			}
+		}
+		taken[b.ChapterPath(chapter, "")] = true
+	}
+}
+
+// titleSlug returns the slug of chapter's title, or chapter-<number> (and
+// true) when it has none.
+func titleSlug(chapter *models.Chapter) (string, bool) {
+	if slug := utils.Slugify(chapter.Title); chapter.Title != "" && slug != utils.UntitledSlug {
+		return slug, false
+	}
+	return fmt.Sprintf("chapter-%d", chapter.ChapterNumber), true
+}
+
+// Link converts an output path into a site-relative link. With PrettyURLs a
+// trailing index.html is dropped so hosts serve the directory index.
+func (b *Builder) Link(outputPath string) string {
+	if !b.PrettyURLs {
+		return outputPath
+	}
+	if outputPath == "index.html" {
+		return "./"
+	}
+	if strings.HasSuffix(outputPath, "/index.html") {
+		return strings.TrimSuffix(outputPath, "index.html")
+	}
+	return outputPath
+}
+
+// BasePath returns the relative prefix ("", "../", "../../", ...) that leads from
+// the page written at outputPath back to the site root.
+func (b *Builder) BasePath(outputPath string) string {
+	return strings.Repeat("../", strings.Count(outputPath, "/"))
+}
+
+// RelURL resolves a site-relative path (optionally with a leading "/") so it works
+// from the page written at pagePath, whatever its depth. Absolute URLs and
+// fragment-only references are returned unchanged.
+func (b *Builder) RelURL(pagePath, target string) string {
+	if isExternal(target) {
+		return target
+	}
+	rel := b.BasePath(pagePath) + strings.TrimLeft(target, "/")
+	if rel == "" {
+		return "./"
+	}
+	return rel
+}
+
+// AbsURL resolves a site-relative path against the base URL. Without a base URL
+// it falls back to RelURL from pagePath so links keep working.
+func (b *Builder) AbsURL(pagePath, target string) string {
+	if isExternal(target) {
+		return target
+	}
+	if b.BaseURL == nil {
+		return b.RelURL(pagePath, target)
+	}
+	ref, err := url.Parse(strings.TrimLeft(target, "/"))
+	if err != nil {
+		return b.RelURL(pagePath, target)
+	}
+	return b.BaseURL.ResolveReference(ref).String()
+}
+
+// CanonicalURL returns the absolute public URL of the page written at pagePath,
+// or "" when no base URL is configured.
+func (b *Builder) CanonicalURL(pagePath string) string {
+	if b.BaseURL == nil {
+		return ""
+	}
+	return b.AbsURL(pagePath, b.Link(pagePath))
+}
+
+// FuncMap returns the URL helpers for templates, bound to the page written at
+// pagePath: relURL, absURL, canonicalURL, chapterURL, volumeURL, novelURL and
+// characterURL.
+// chapterURL takes an optional variant name ("styled" for the Bulma version) and
+// links to the primary variant without one.
+func (b *Builder) FuncMap(pagePath string) template.FuncMap {
+	return template.FuncMap{
+		"relURL":       func(target string) string { return b.RelURL(pagePath, target) },
+		"absURL":       func(target string) string { return b.AbsURL(pagePath, target) },
+		"canonicalURL": func() string { return b.CanonicalURL(pagePath) },
+		"chapterURL": func(chapter *models.Chapter, variant ...string) (string, error) {
+			if chapter == nil {
+				return "", nil
+			}
+			if len(variant) == 0 {
+				return b.RelURL(pagePath, chapter.URL), nil
+			}
+			link, ok := chapter.URLs[variant[0]]
+			if !ok {
+				return "", fmt.Errorf("chapterURL: unknown variant %q", variant[0])
+			}
+			return b.RelURL(pagePath, link), nil
+		},
+		"volumeURL": func(volume *models.Volume) string {
+			if volume == nil {
+				return ""
+			}
+			return b.RelURL(pagePath, volume.URL)
+		},
+		"novelURL": func(novel *models.Novel) string {
+			if novel == nil {
+				return ""
+			}
+			return b.RelURL(pagePath, novel.URL)
+		},
+		"characterURL": func(character *models.Character) string {
+			if character == nil {
+				return ""
+			}
+			return b.RelURL(pagePath, character.URL)
+		},
+	}
+}
+
+// isExternal reports whether target already points somewhere on its own:
+// a full URL, a protocol-relative URL, a mailto: link or an in-page fragment.
+func isExternal(target string) bool {
+	if strings.HasPrefix(target, "#") || strings.HasPrefix(target, "//") {
+		return true
+	}
+	u, err := url.Parse(target)
+	return err == nil && u.Scheme != ""
+}
@@ -0,0 +1,97 @@
+package urls
+
+import (
+	"NovelStaticGenerator/internal/models"
+	"testing"
+)
+
+func chapter(id, volume, number int, title string) *models.Chapter {
+	return &models.Chapter{ID: id, NovelSlug: "saga", VolumeNumber: volume, ChapterNumber: number, Title: title}
+}
+
+func TestAssignTitleSlugs(t *testing.T) {
+	tests := []struct {
+		permalink string
+		want      []string
+	}{
+		{"{novel}/{title_slug}", []string{
+			"saga/prologo/index.html",
+			"saga/the-duel/index.html",
+			"saga/prologo-v2-c1/index.html",
+			"saga/chapter-2/index.html",
+			"saga/chapter-v2-c2/index.html",
+			"saga/chapter-3/index.html",
+			"saga/the-duel-v2-c4/index.html",
+			"saga/the-duel-v2-c4-8/index.html",
+		}},
+		// Numbered by volume, the same titles no longer collide
+		{"{novel}/v{volume}/{title_slug}.html", []string{
+			"saga/v1/prologo.html",
+			"saga/v1/the-duel.html",
+			"saga/v2/prologo.html",
+			"saga/v1/chapter-2.html",
+			"saga/v2/chapter-2.html",
+			"saga/v2/chapter-3.html",
+			"saga/v2/the-duel-v2-c4.html",
+			"saga/v2/the-duel.html",
+		}},
+	}
+	for _, tt := range tests {
+		b, err := NewBuilder(tt.permalink, false, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		chapters := []*models.Chapter{
+			chapter(1, 1, 1, "Prólogo"),
+			chapter(2, 1, 3, "The Duel"),
+			chapter(3, 2, 1, "Prólogo"),
+			chapter(4, 1, 2, ""),
+			chapter(5, 2, 2, "決闘"), // Nothing a slug keeps
+			chapter(6, 2, 3, "Chapter 3"),
+			chapter(7, 2, 4, "The Duel V2 C4"),
+			chapter(8, 2, 4, "The Duel"), // Same number as 7, in a broken import
+		}
+		b.AssignTitleSlugs(chapters, make(map[string]bool))
+		for i, ch := range chapters {
+			if got := b.ChapterPath(ch, ""); got != tt.want[i] {
+				t.Errorf("%s: chapter %d path = %q, want %q", tt.permalink, ch.ID, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestAssignTitleSlugsAcrossNovels(t *testing.T) {
+	b, err := NewBuilder("{title_slug}.html", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := make(map[string]bool)
+	first, second := chapter(1, 1, 1, "Prologue"), chapter(2, 1, 1, "Prologue")
+	second.NovelSlug = "other"
+	b.AssignTitleSlugs([]*models.Chapter{first}, taken)
+	b.AssignTitleSlugs([]*models.Chapter{second}, taken)
+	if first.TitleSlug != "prologue" || second.TitleSlug != "prologue-v1-c1" {
+		t.Errorf("slugs = %q, %q, want prologue, prologue-v1-c1", first.TitleSlug, second.TitleSlug)
+	}
+}
+
+func TestChapterPath(t *testing.T) {
+	tests := []struct {
+		permalink, suffix, want string
+	}{
+		{"", "", "saga/v1-c2.html"},
+		{"", "-styled", "saga/v1-c2-styled.html"},
+		{"{novel}/{volume}/{chapter}/", "-styled", "saga/1/2-styled/index.html"},
+		{"{novel}/{chapter_id}{variant}.htm", "-styled", "saga/9-styled.htm"},
+		{"{novel}/{title_slug}", "", "saga/the-duel/index.html"},
+	}
+	for _, tt := range tests {
+		b, err := NewBuilder(tt.permalink, false, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := b.ChapterPath(chapter(9, 1, 2, "The Duel"), tt.suffix); got != tt.want {
+			t.Errorf("ChapterPath(%q, %q) = %q, want %q", tt.permalink, tt.suffix, got, tt.want)
+		}
+	}
+}
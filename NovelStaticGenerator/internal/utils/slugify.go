@@ -17,12 +17,22 @@ var (
     maxSlugLength        = 50 // Define the maximum length
 )
 
+// Fold lower-cases s and strips its accents ("Tío" becomes "tio"), so text
+// can be compared the way readers expect. Slugify and the search index use it.
+func Fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	normalized, _, _ := transform.String(t, s)
+	return strings.ToLower(normalized)
+}
+
+// UntitledSlug is what Slugify returns for text it keeps nothing of, such as
+// a title in Japanese.
+const UntitledSlug = "untitled"
+
 // Slugify creates a URL-friendly "slug" from a given string, truncated to maxSlugLength.
 func Slugify(s string) string {
 	// ... (keep steps 1-5: normalize, lowercase, replace spaces, clean, single dash) ...
-    t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
-	normalized, _, _ := transform.String(t, s)
-	lower := strings.ToLower(normalized)
+	lower := Fold(s)
     // Allow spaces temporarily to handle multi-word truncation better
 	withHyphens := strings.ReplaceAll(lower, " ", "-") // Initial space replace
     cleaned := nonAlphanumericRegex.ReplaceAllString(withHyphens, "")
@@ -47,7 +57,7 @@ func Slugify(s string) string {
 
 	// Ensure it's not empty after truncation/trimming
 	if trimmed == "" {
-		return "untitled" // Or some default
+		return UntitledSlug
 	}
 
 	return trimmed
@@ -0,0 +1,170 @@
+package variants
+
+import (
+	"NovelStaticGenerator/internal/database"
+	"NovelStaticGenerator/internal/formatter"
+	"NovelStaticGenerator/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Ways a variant can show footnotes and translator notes.
+const (
+	FootnoteEndnotes = "endnotes" // Numbered references to a list at the end of the chapter
+	FootnotePopovers = "popovers" // The same list, and each note in a popover from its reference
+)
+
+// DefaultTemplate is the page template chapters are rendered with when a
+// variant names none.
+const DefaultTemplate = "chapter"
+
+// Default returns the historical pair of variants: the plain HTML version and
+// the Bulma-styled one with a "-styled" filename suffix.
+func Default() []*models.Variant {
+	return []*models.Variant{
+		{
+			Name:     "plain",
+			Label:    "Plain HTML",
+			Content:  "content_html",
+			Template: DefaultTemplate,
+		},
+		{
+			Name:        "styled",
+			Label:       "Styled (Bulma)",
+			Content:     "content_bulma",
+			Suffix:      "-styled",
+			Stylesheets: []string{"css/bulma.css", "css/footnotes.css"},
+			Template:    DefaultTemplate,
+			Params:      map[string]string{"button_class": "button is-link", "footnotes_class": "section content is-small"},
+			Footnotes:   FootnotePopovers,
+		},
+	}
+}
+
+// Load reads the variants from a JSON file holding an array of variants:
+//
+//	[
+//	  {"name": "plain", "label": "Plain HTML", "content": "content_html"},
+//	  {"name": "sepia", "label": "Sepia", "content": "content_bulma", "suffix": "-sepia",
+//	   "stylesheets": ["css/bulma.css", "css/sepia.css"]},
+//	  {"name": "large", "label": "Large print", "format": "styled", "suffix": "-large",
+//	   "stylesheets": ["css/bulma.css", "css/large.css"]}
+//	]
+//
+// A variant with a format is rendered from content_plain by the formatter
+// package in that style rather than read from a content column.
+//
+// Stored content is sanitized with the variant's "sanitize" policy, e.g.
+// {"tags": ["p", "br", "strong"], "attributes": {"*": ["class"]}, "schemes": ["https"]};
+// without one sanitize.DefaultPolicy applies.
+//
+// Footnotes are listed at the end of each chapter; with "footnotes": "popovers"
+// their references also open them in place. The "footnotes_class" parameter
+// sets the class of the list.
+//
+// The first variant is the primary one: index, novel and content pages are
+// rendered in it, and the sitemap and feeds link to it. An empty path returns
+// Default().
+func Load(path string) ([]*models.Variant, error) {
+	if path == "" {
+		return Default(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read variants file '%s': %w", path, err)
+	}
+	var list []*models.Variant
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("could not parse variants file '%s': %w", path, err)
+	}
+	if err := Validate(list); err != nil {
+		return nil, fmt.Errorf("variants file '%s': %w", path, err)
+	}
+	return list, nil
+}
+
+// Validate fills in defaults and checks that the variants can all be written:
+// names and suffixes must be unique, and content must name a chapter column
+// unless format names a formatter style.
+func Validate(list []*models.Variant) error {
+	if len(list) == 0 {
+		return errors.New("at least one variant is required")
+	}
+	names := make(map[string]bool, len(list))
+	suffixes := make(map[string]string, len(list))
+	for i, v := range list {
+		if v == nil || strings.TrimSpace(v.Name) == "" {
+			return fmt.Errorf("variant %d has no name", i+1)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("variant name %q is used twice", v.Name)
+		}
+		names[v.Name] = true
+		if other, ok := suffixes[v.Suffix]; ok {
+			return fmt.Errorf("variants %q and %q have the same suffix %q and would overwrite each other", other, v.Name, v.Suffix)
+		}
+		suffixes[v.Suffix] = v.Name
+		if v.Format != "" {
+			if _, err := formatter.Lookup(v.Format); err != nil {
+				return fmt.Errorf("variant %q: %w", v.Name, err)
+			}
+		} else if !slices.Contains(database.ContentColumns, v.Content) {
+			return fmt.Errorf("variant %q: content %q is not one of %s", v.Name, v.Content, strings.Join(database.ContentColumns, ", "))
+		}
+		switch v.Footnotes {
+		case "":
+			v.Footnotes = FootnoteEndnotes
+		case FootnoteEndnotes, FootnotePopovers:
+		default:
+			return fmt.Errorf("variant %q: footnotes %q is not %q or %q", v.Name, v.Footnotes, FootnoteEndnotes, FootnotePopovers)
+		}
+		if v.Label == "" {
+			v.Label = v.Name
+		}
+		if v.Template == "" {
+			v.Template = DefaultTemplate
+		}
+	}
+	return nil
+}
+
+// Columns lists the content columns the variants read, without duplicates.
+func Columns(list []*models.Variant) []string {
+	var columns []string
+	for _, v := range list {
+		column := v.Content
+		if v.Format != "" {
+			column = database.PlainColumn
+		}
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// columnStyles are the formatter styles the publisher wrote each column in.
+var columnStyles = map[string]string{
+	"content_html":  "plain",
+	"content_bulma": "styled",
+}
+
+// Reformat makes every variant render from content_plain: variants reading a
+// column the publisher wrote get that column's style as their format.
+func Reformat(list []*models.Variant) error {
+	for _, v := range list {
+		if v.Format != "" {
+			continue
+		}
+		style, ok := columnStyles[v.Content]
+		if !ok {
+			return fmt.Errorf("variant %q: no format style matches content %q; set its format", v.Name, v.Content)
+		}
+		v.Format = style
+	}
+	return nil
+}
@@ -1,84 +1,310 @@
 package main
 
 import (
-	"html/template"
-	"log"
-	"NovelStaticGenerator/internal/config"    // Adjust import path
-	"NovelStaticGenerator/internal/database" // Adjust import path
+	"NovelStaticGenerator/internal/assets"
+	"NovelStaticGenerator/internal/config" // Adjust import path
+	"NovelStaticGenerator/internal/content"
+	"NovelStaticGenerator/internal/database"  // Adjust import path
 	"NovelStaticGenerator/internal/generator" // Adjust import path
+	"NovelStaticGenerator/internal/i18n"
+	"NovelStaticGenerator/internal/logging"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/templatefuncs"
+	"NovelStaticGenerator/internal/theme"
+	"NovelStaticGenerator/internal/typography"
+	"NovelStaticGenerator/internal/urls"
+	"NovelStaticGenerator/internal/variants"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
+	"strings"
 )
 
-const (
-	templatesDir = "templates" // Directory containing HTML templates
-	staticDir    = "static"    // Directory containing static assets (CSS, JS, images)
-)
+// builtinThemeFS holds the default theme compiled into the binary. User themes
+// given with -theme are layered over it file by file.
+//
+//go:embed all:themes/default
+var builtinThemeFS embed.FS
+
+// builtinTheme returns the default theme rooted at its layouts/partials/static directories.
+func builtinTheme() fs.FS {
+	sub, err := fs.Sub(builtinThemeFS, builtinThemeDir)
+	if err != nil {
+		panic(err) // The embedded path is fixed at compile time
+	}
+	return sub
+}
+
+// builtinThemeDir is the source directory of the built-in theme, relative to
+// the module root.
+const builtinThemeDir = "themes/default"
+
+// themeBase returns the theme layer that -theme is laid over. It is the
+// embedded theme, except in the preview server started from a source checkout,
+// which reads builtinThemeDir from disk so that edits to it show up.
+var themeBase = builtinTheme
+
+// commands maps each subcommand to its implementation. "build" runs when no
+// command is given.
+var commands = map[string]func(cfg *config.Config){
+	"build":           runBuild,
+	"check-templates": runCheckTemplates,
+	"import":          runImport,
+	"sanitize-report": runSanitizeReport,
+	"serve":           runServe,
+	"watch":           runWatch,
+}
 
 func main() {
-	log.Println("Starting Novel Static Site Generator...")
+	command, args := "build", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	run, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q (available: %s)\n", command, strings.Join(commandNames(), ", "))
+		os.Exit(2)
+	}
 
 	// 1. Load Configuration
-	cfg, err := config.LoadConfig()
+	cfg, err := config.LoadConfig(command, args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	if _, err := logging.Setup(os.Stderr, cfg.LogLevel, cfg.LogFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring logging: %v\n", err)
+		os.Exit(1)
+	}
+
+	run(cfg)
+}
+
+// commandNames lists the available subcommands, sorted.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runBuild generates the site from the database into the output directory.
+func runBuild(cfg *config.Config) {
+	if err := cfg.RequireDatabase(); err != nil {
+		fatal("invalid configuration", err)
+	}
+	if err := cfg.RequireOutput(); err != nil {
+		fatal("invalid configuration", err)
 	}
-	log.Printf("Configuration loaded. Output directory: %s", cfg.OutputDir)
+	slog.Info("starting Novel Static Site Generator", "output", cfg.OutputDir)
 
-	// 2. Connect to Database
+	// 2. Load everything from the database, the theme and the content pages
+	gen := loadSite(cfg)
+	if gen == nil {
+		return // Nothing to process
+	}
+
+	// 3. Run Generation Process
+	if err := gen.GenerateSite(); err != nil {
+		fatal("error during site generation", err)
+	}
+
+	slog.Info("Novel Static Site Generator finished successfully")
+}
+
+// loadSite fetches the novels, chapters and characters from the database and
+// returns a generator for them with the configured theme, variants and content
+// pages, or nil when the database has no chapters.
+func loadSite(cfg *config.Config) *generator.SiteGenerator {
 	db, err := database.ConnectDB(cfg.DSN())
 	if err != nil {
-		log.Fatalf("Error connecting to database: %v", err)
+		fatal("error connecting to database", err)
 	}
-	defer db.Close() // Ensure database connection is closed when main exits
+	defer db.Close() // Every row is loaded up front; generation does not need the connection
 
-	// 3. Fetch Chapters
-	chapters, err := database.FetchAllChapters(db)
+	chapterVariants, err := loadVariants(cfg)
+	if err != nil {
+		fatal("error loading variants", err)
+	}
+	novels, err := database.FetchAllNovels(db)
+	if err != nil {
+		fatal("error fetching novels", err)
+	}
+	chapters, err := database.FetchAllChapters(db, variants.Columns(chapterVariants))
 	if err != nil {
-		log.Fatalf("Error fetching chapters: %v", err)
+		fatal("error fetching chapters", err)
+	}
+	characters, err := database.FetchAllCharacters(db)
+	if err != nil {
+		fatal("error fetching characters", err)
 	}
 	if len(chapters) == 0 {
-		log.Println("No chapters fetched from the database. Exiting.")
-		os.Exit(0) // Exit gracefully if there's nothing to process
+		slog.Info("no chapters fetched from the database, exiting")
+		return nil
+	}
+
+	gen, err := newGenerator(cfg, novels, chapters)
+	if err != nil {
+		fatal("error setting up the generator", err, "theme", cfg.Theme)
+	}
+	gen.Variants = chapterVariants
+	gen.Characters = characters
+	gen.Pages, err = content.LoadPages(cfg.ContentDir)
+	if err != nil {
+		fatal("error loading content pages", err)
 	}
+	return gen
+}
 
-	// 4. Parse HTML Templates
-	// Use Funcs to add custom template functions if needed later
-	// E.g., funcs := template.FuncMap{"customFunc": myCustomFunc}
-	tpl, err := loadTemplates(templatesDir)
+// newGenerator loads the configured theme and URL settings and returns a
+// generator for novels and chapters writing to cfg.OutputDir. The preview
+// server calls it again for every rebuild, so it reports errors rather than
+// exiting.
+func newGenerator(cfg *config.Config, novels []*models.Novel, chapters []*models.Chapter) (*generator.SiteGenerator, error) {
+	th, err := theme.Load(cfg.Theme, themeBase())
+	if err != nil {
+		return nil, fmt.Errorf("could not load theme: %w", err)
+	}
+	static, err := th.Static()
+	if err != nil {
+		return nil, fmt.Errorf("could not load theme static assets: %w", err)
+	}
+	manifest, err := assets.Build(static, assets.Options{Minify: cfg.Minify, Fingerprint: cfg.Fingerprint})
+	if err != nil {
+		return nil, fmt.Errorf("could not process theme static assets: %w", err)
+	}
+	urlBuilder, err := urls.NewBuilder(cfg.Permalink, cfg.PrettyURLs, cfg.BaseURL)
 	if err != nil {
-		log.Fatalf("Error parsing templates from '%s': %v", templatesDir, err)
+		return nil, fmt.Errorf("invalid URL configuration: %w", err)
 	}
-	
-	// 5. Initialize Site Generator
-	gen := generator.NewSiteGenerator(chapters, cfg.OutputDir, tpl, staticDir)
+	catalog, err := i18n.Load(th.FS())
+	if err != nil {
+		return nil, fmt.Errorf("could not load message catalogs: %w", err)
+	}
+	// The URL and asset helpers and T are rebound to the page being rendered by the
+	// generator; the root-level versions here only make the names known to the parser.
+	tpl, err := loadTemplates(th, templatefuncs.New(urlBuilder, catalog, manifest, urlBuilder.IndexPath(), cfg.Lang))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse templates: %w", err)
+	}
+	gen := generator.NewSiteGenerator(novels, chapters, cfg.OutputDir, tpl, manifest, urlBuilder)
+	gen.Lang = cfg.Lang
+	gen.PurgeCSS = cfg.PurgeCSS
+	gen.Sanitize = cfg.Sanitize
+	gen.Search = cfg.Search
+	if gen.Typography, err = typography.ParseRules(cfg.Typography); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	gen.PurgeSafelist = splitList(cfg.Safelist)
+	gen.Catalog = catalog
+	return gen, nil
+}
 
-	// 6. Run Generation Process
-	err = gen.GenerateSite()
+// loadVariants loads the configured chapter variants, switched to formatting
+// content_plain when -reformat is set.
+func loadVariants(cfg *config.Config) ([]*models.Variant, error) {
+	list, err := variants.Load(cfg.Variants)
 	if err != nil {
-		log.Fatalf("Error during site generation: %v", err)
+		return nil, err
+	}
+	if cfg.Reformat {
+		if err := variants.Reformat(list); err != nil {
+			return nil, err
+		}
 	}
+	return list, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
 
-	log.Println("Novel Static Site Generator finished successfully.")
+// fatal logs msg with err at error level and exits with a non-zero status.
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append([]any{"error", err}, args...)...)
+	os.Exit(1)
 }
 
-func loadTemplates(templatesDir string) (map[string]*template.Template, error) {
-	pages := []string{"index.html", "chapter.html"} // add your page-specific templates here
-	base := filepath.Join(templatesDir, "_base.html")
+// loadTemplates parses every page template of the theme. Each page gets its own
+// set made of the base layout, every partial (partials/*.html and layouts/_*.html)
+// and the page itself, keyed by the page path under layouts/ without its
+// extension (e.g. "chapter", or "novels/<slug>/chapter" for a per-novel override).
+// An override directory may carry its own _base.html and _*.html partials.
+func loadTemplates(th *theme.Theme, funcs template.FuncMap) (map[string]*template.Template, error) {
+	pages, err := th.Layouts()
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("theme has no page templates in %s/", theme.LayoutsDir)
+	}
+	partials, err := th.Partials()
+	if err != nil {
+		return nil, err
+	}
 
 	tmpls := make(map[string]*template.Template)
 
 	for _, page := range pages {
-		name := page[:len(page)-len(filepath.Ext(page))] // strip .html
-		pagePath := filepath.Join(templatesDir, page)
+		name := strings.TrimSuffix(page, path.Ext(page)) // strip .html
 
-		tmpl, err := template.New(name).ParseFiles(base, pagePath)
-		if err != nil {
-			return nil, err
+		base := path.Join(theme.LayoutsDir, theme.BaseLayout)
+		files := append([]string{base}, partials...)
+		if dir := path.Dir(page); dir != "." {
+			if override := path.Join(theme.LayoutsDir, dir, theme.BaseLayout); th.Exists(override) {
+				files[0] = override
+			}
+			dirPartials, err := th.LayoutPartials(dir)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, dirPartials...)
+		}
+		files = append(files, path.Join(theme.LayoutsDir, page))
+
+		tmpl := template.New(name).Funcs(funcs)
+		for _, file := range files {
+			if err := parseThemeFile(tmpl, th, file); err != nil {
+				return nil, err
+			}
 		}
 
 		tmpls[name] = tmpl
 	}
 
+	slog.Debug("loaded templates", "pages", len(tmpls), "partials", len(partials))
 	return tmpls, nil
-}
\ No newline at end of file
+}
+
+// parseThemeFile parses one theme file into set under its base name, the way
+// template.ParseFiles names templates.
+func parseThemeFile(set *template.Template, th *theme.Theme, file string) error {
+	content, err := th.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("could not read template '%s': %w", file, err)
+	}
+	if _, err := set.New(path.Base(file)).Parse(string(content)); err != nil {
+		return fmt.Errorf("could not parse template '%s': %w", file, err)
+	}
+	return nil
+}
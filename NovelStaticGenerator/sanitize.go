@@ -0,0 +1,31 @@
+package main
+
+import (
+	"NovelStaticGenerator/internal/config"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// runSanitizeReport lists what sanitizing removes from each chapter without
+// writing the site, so bad imports can be found and fixed at the source. It
+// needs the database but no output directory.
+func runSanitizeReport(cfg *config.Config) {
+	if err := cfg.RequireDatabase(); err != nil {
+		fatal("invalid configuration", err)
+	}
+	gen := loadSite(cfg)
+	if gen == nil {
+		return
+	}
+	gen.Sanitize = true
+	if _, err := gen.Prepare(); err != nil {
+		fatal("error preparing chapters", err)
+	}
+
+	removals := gen.Removals()
+	for _, removal := range removals {
+		fmt.Fprintln(os.Stdout, removal)
+	}
+	slog.Info("sanitize report finished", "removals", len(removals), "chapters", len(gen.Chapters))
+}
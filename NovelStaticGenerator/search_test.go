@@ -0,0 +1,96 @@
+package main
+
+import (
+	"NovelStaticGenerator/internal/config"
+	"NovelStaticGenerator/internal/fixtures"
+	"NovelStaticGenerator/internal/generator"
+	"NovelStaticGenerator/internal/search"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestSearchIndexLayout builds the fixture site and checks its search files
+// the way static/js/search.js reads them: the page names the manifest, the
+// manifest lists the documents and the shards, and each term is in the shard
+// search.ShardName gives it.
+func TestSearchIndexLayout(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-pretty-urls", "-permalink", "{novel}/{title_slug}/"},
+	} {
+		cfg, err := config.LoadConfig("build", append([]string{"-search", "-content", t.TempDir()}, args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chapterVariants, err := loadVariants(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		novels, chapters := fixtures.Novels()
+		gen, err := newGenerator(cfg, novels, chapters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gen.Variants = chapterVariants
+		gen.Pages = fixtures.Pages()
+		gen.Characters = fixtures.Characters()
+		site := generator.NewMemoryOutput()
+		gen.Output = site
+		if err := gen.GenerateSite(); err != nil {
+			t.Fatal(err)
+		}
+
+		page, ok := site.File("search.html")
+		if !ok || !strings.Contains(string(page), `data-index="search/index.json"`) {
+			t.Fatalf("%v: search.html missing or not pointing at search/index.json", args)
+		}
+		data, ok := site.File("search/index.json")
+		if !ok {
+			t.Fatalf("%v: search/index.json not written", args)
+		}
+		var manifest struct {
+			Version int
+			Docs    [][3]string
+			Shards  []string
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			t.Fatal(err)
+		}
+		if manifest.Version != search.Version || len(manifest.Docs) != len(chapters) || len(manifest.Shards) == 0 {
+			t.Fatalf("%v: manifest has version %d, %d docs and %d shards; want version %d and one doc per chapter (%d)",
+				args, manifest.Version, len(manifest.Docs), len(manifest.Shards), search.Version, len(chapters))
+		}
+		for _, doc := range manifest.Docs {
+			target := doc[0]
+			if target == "" || strings.HasSuffix(target, "/") {
+				target += "index.html"
+			}
+			if _, ok := site.File(target); !ok || doc[1] == "" {
+				t.Errorf("%v: document %q links to no page or has no title", args, doc)
+			}
+		}
+
+		for _, name := range manifest.Shards {
+			data, ok := site.File("search/" + name + ".json")
+			if !ok {
+				t.Errorf("%v: shard %s listed but not written", args, name)
+				continue
+			}
+			var terms map[string][][]int
+			if err := json.Unmarshal(data, &terms); err != nil {
+				t.Fatal(err)
+			}
+			for term, entries := range terms {
+				if search.ShardName(term) != name {
+					t.Errorf("%v: term %q is in shard %s, want %s", args, term, name, search.ShardName(term))
+				}
+				for _, entry := range entries {
+					if len(entry) < 2 || entry[0] < 0 || entry[0] >= len(manifest.Docs) {
+						t.Errorf("%v: term %q has the bad posting %v", args, term, entry)
+					}
+				}
+			}
+		}
+	}
+}
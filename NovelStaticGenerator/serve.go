@@ -0,0 +1,115 @@
+package main
+
+import (
+	"NovelStaticGenerator/internal/config"
+	"NovelStaticGenerator/internal/content"
+	"NovelStaticGenerator/internal/devserver"
+	"NovelStaticGenerator/internal/generator"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runServe builds the site into memory and serves it on cfg.Serve.Addr for
+// previewing. The novels and chapters are read from the database once; the
+// theme and the content pages are read again for every build, which happens
+// whenever a file under the -theme or content directory changes, and the open
+// pages then reload. A build that fails is logged and the last good one stays
+// up, so a typo in a template does not stop the server.
+//
+// The built-in theme is compiled into the binary, so when the server runs from
+// a source checkout it reads and watches themes/default on disk instead;
+// anywhere else, edits to the built-in theme need a new binary.
+func runServe(cfg *config.Config) {
+	if err := cfg.RequireDatabase(); err != nil {
+		fatal("invalid configuration", err)
+	}
+	dirs := []string{cfg.ContentDir}
+	if cfg.Theme != "" {
+		dirs = append(dirs, cfg.Theme)
+	}
+	if info, err := os.Stat(builtinThemeDir); err == nil && info.IsDir() {
+		themeBase = func() fs.FS { return os.DirFS(builtinThemeDir) }
+		dirs = append(dirs, builtinThemeDir)
+	} else {
+		slog.Info("the built-in theme is compiled in and not watched; run from the source checkout, or copy it and pass -theme, to preview changes to it")
+	}
+	data := loadSite(cfg)
+	if data == nil {
+		return
+	}
+
+	srv := devserver.New()
+	build := func() error {
+		start := time.Now()
+		gen, err := newGenerator(cfg, data.Novels, data.Chapters)
+		if err != nil {
+			return err
+		}
+		gen.Variants = data.Variants
+		gen.Characters = data.Characters
+		if gen.Pages, err = content.LoadPages(cfg.ContentDir); err != nil {
+			return fmt.Errorf("could not load content pages: %w", err)
+		}
+		site := generator.NewMemoryOutput()
+		gen.Output = site
+		if err := gen.GenerateSite(); err != nil {
+			return err
+		}
+		srv.Publish(site)
+		slog.Info("preview updated", "files", len(site.Paths()), "duration", time.Since(start))
+		return nil
+	}
+	if err := build(); err != nil {
+		fatal("error during site generation", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go devserver.Watch(ctx, cfg.Serve.Poll, dirs, func() {
+		slog.Info("change detected, rebuilding", "dirs", dirs)
+		if err := build(); err != nil {
+			slog.Error("rebuild failed, still serving the last good build", "error", err)
+		}
+	})
+
+	httpServer := &http.Server{
+		Addr:              cfg.Serve.Addr,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx }, // Ends the reload streams on shutdown
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdown)
+	}()
+
+	slog.Info("serving the site preview", "url", previewURL(cfg.Serve.Addr), "watching", dirs)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fatal("preview server failed", err, "addr", cfg.Serve.Addr)
+	}
+	slog.Info("preview server stopped")
+}
+
+// previewURL returns the URL of the site served on addr, such as ":8080".
+func previewURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr + "/"
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/"
+}
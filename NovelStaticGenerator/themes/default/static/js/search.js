@@ -0,0 +1,181 @@
+// Searches the chapter index written by the generator (internal/search). The
+// manifest lists the documents and shards; each query downloads only the
+// shards of its words. Words are folded and split exactly like
+// search.Tokenize does in Go, or nothing would match.
+//
+// A query finds the chapters containing all of its words, the last one as a
+// prefix so results follow typing. In double quotes it finds the words next to
+// each other, in that order.
+(function () {
+    "use strict";
+
+    var MIN_TERM = 2, MAX_TERM = 40, SHARD_PREFIX = 2, MAX_RESULTS = 50;
+
+    var form = document.getElementById("search-form");
+    var input = document.getElementById("search-input");
+    var status = document.getElementById("search-status");
+    var list = document.getElementById("search-results");
+    if (!form || !input || !status || !list) {
+        return;
+    }
+
+    var indexURL = new URL(form.getAttribute("data-index"), document.baseURI);
+    var siteRoot = new URL("../", indexURL);
+    var manifest = null;
+    var shards = {};
+
+    function fold(text) {
+        return text.normalize("NFD").replace(/\p{Mn}/gu, "").normalize("NFC").toLowerCase();
+    }
+
+    // tokenize returns the indexable terms of text with their word positions.
+    function tokenize(text) {
+        var tokens = [];
+        var words = fold(text).split(/[^\p{L}\p{N}]+/u).filter(function (w) { return w !== ""; });
+        words.forEach(function (word, position) {
+            var n = Array.from(word).length;
+            if (n >= MIN_TERM && n <= MAX_TERM) {
+                tokens.push({ term: word, position: position });
+            }
+        });
+        return tokens;
+    }
+
+    function shardName(term) {
+        var prefix = Array.from(term).slice(0, SHARD_PREFIX).join("");
+        if (/^[a-z0-9]+$/.test(prefix)) {
+            return prefix;
+        }
+        var hex = "";
+        new TextEncoder().encode(prefix).forEach(function (b) {
+            hex += (b < 16 ? "0" : "") + b.toString(16);
+        });
+        return "_" + hex;
+    }
+
+    function load(url) {
+        return fetch(url).then(function (response) {
+            if (!response.ok) {
+                throw new Error(response.status + " " + url);
+            }
+            return response.json();
+        });
+    }
+
+    function loadShard(name) {
+        if (!shards[name]) {
+            shards[name] = manifest.shards.indexOf(name) < 0 ? Promise.resolve({}) : load(new URL(name + ".json", indexURL));
+        }
+        return shards[name];
+    }
+
+    // postings returns the positions of term (or of every term starting with
+    // it) by document.
+    function postings(shard, term, prefix) {
+        var byDoc = {};
+        Object.keys(shard).forEach(function (key) {
+            if (key !== term && !(prefix && key.lastIndexOf(term, 0) === 0)) {
+                return;
+            }
+            shard[key].forEach(function (entry) {
+                var doc = entry[0], position = 0, positions = byDoc[doc] || (byDoc[doc] = []);
+                for (var i = 1; i < entry.length; i++) {
+                    position += entry[i];
+                    positions.push(position);
+                }
+            });
+        });
+        return byDoc;
+    }
+
+    function search(query) {
+        var phrase = /^\s*".*"\s*$/.test(query);
+        var tokens = tokenize(query);
+        if (tokens.length === 0) {
+            return Promise.resolve([]);
+        }
+        return Promise.all(tokens.map(function (t) { return loadShard(shardName(t.term)); })).then(function (loaded) {
+            var lists = tokens.map(function (t, i) {
+                return postings(loaded[i], t.term, !phrase && i === tokens.length - 1);
+            });
+            var results = [];
+            Object.keys(lists[0]).forEach(function (doc) {
+                var score = 0;
+                for (var i = 0; i < lists.length; i++) {
+                    if (!lists[i][doc]) {
+                        return;
+                    }
+                    score += lists[i][doc].length;
+                }
+                if (phrase) {
+                    score = lists[0][doc].filter(function (start) {
+                        return tokens.every(function (t, i) {
+                            return lists[i][doc].indexOf(start + t.position - tokens[0].position) >= 0;
+                        });
+                    }).length;
+                    if (score === 0) {
+                        return;
+                    }
+                }
+                results.push({ doc: manifest.docs[doc], score: score });
+            });
+            results.sort(function (a, b) { return b.score - a.score; });
+            return results;
+        });
+    }
+
+    function show(query, results) {
+        list.textContent = "";
+        status.textContent = results.length === 0
+            ? form.getAttribute("data-none")
+            : form.getAttribute("data-count").replace("%d", results.length);
+        results.slice(0, MAX_RESULTS).forEach(function (result) {
+            var item = document.createElement("li");
+            var link = document.createElement("a");
+            link.href = new URL(result.doc[0], siteRoot).href;
+            link.textContent = result.doc[1];
+            var excerpt = document.createElement("p");
+            excerpt.textContent = result.doc[2];
+            item.appendChild(link);
+            item.appendChild(excerpt);
+            list.appendChild(item);
+        });
+    }
+
+    function run() {
+        var query = input.value;
+        if (query.trim() === "") {
+            list.textContent = "";
+            status.textContent = "";
+            return;
+        }
+        status.textContent = form.getAttribute("data-loading");
+        (manifest ? Promise.resolve(manifest) : load(indexURL)).then(function (m) {
+            manifest = m;
+            return search(query);
+        }).then(function (results) {
+            if (input.value === query) {
+                show(query, results);
+            }
+        }).catch(function () {
+            status.textContent = form.getAttribute("data-error");
+        });
+    }
+
+    var timer = null;
+    input.addEventListener("input", function () {
+        clearTimeout(timer);
+        timer = setTimeout(run, 200);
+    });
+    form.addEventListener("submit", function (event) {
+        event.preventDefault();
+        history.replaceState(null, "", "?q=" + encodeURIComponent(input.value));
+        run();
+    });
+
+    var initial = new URLSearchParams(location.search).get("q");
+    if (initial) {
+        input.value = initial;
+        run();
+    }
+})();
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"NovelStaticGenerator/internal/config"
+	"NovelStaticGenerator/internal/content"
+	"NovelStaticGenerator/internal/database"
+	"NovelStaticGenerator/internal/models"
+	"NovelStaticGenerator/internal/variants"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// runWatch builds the site into the output directory and keeps it up to date
+// while the publisher adds chapters: every cfg.Watch.Interval it compares the
+// row counts and last updates of the novels, volumes and chapters tables, and
+// when they change it works out which novels changed, fetches their chapters
+// again and rewrites their pages along with the pages listing every novel
+// (index, statistics, search, characters, sitemap and feeds). The pages of
+// other novels are left as they are. A failed poll or rebuild is logged and
+// tried again at the next interval.
+func runWatch(cfg *config.Config) {
+	if err := cfg.RequireDatabase(); err != nil {
+		fatal("invalid configuration", err)
+	}
+	if err := cfg.RequireOutput(); err != nil {
+		fatal("invalid configuration", err)
+	}
+	chapterVariants, err := loadVariants(cfg)
+	if err != nil {
+		fatal("error loading variants", err)
+	}
+	db, err := database.ConnectDB(cfg.DSN())
+	if err != nil {
+		fatal("error connecting to database", err)
+	}
+	defer db.Close()
+
+	tracker, err := database.NewChangeTracker(db)
+	if err != nil {
+		fatal("error setting up change tracking", err)
+	}
+	summary, err := tracker.Summary()
+	if err != nil {
+		fatal("error polling the database", err)
+	}
+	novelSummaries, err := tracker.Novels()
+	if err != nil {
+		fatal("error polling the database", err)
+	}
+	chapters, err := database.FetchAllChapters(db, variants.Columns(chapterVariants))
+	if err != nil {
+		fatal("error fetching chapters", err)
+	}
+	if err := watchBuild(cfg, db, chapterVariants, chapters, nil); err != nil {
+		fatal("error during site generation", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	slog.Info("watching the database for changes", "interval", cfg.Watch.Interval)
+	ticker := time.NewTicker(cfg.Watch.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("watch stopped")
+			return
+		case <-ticker.C:
+		}
+
+		current, err := tracker.Summary()
+		if err != nil {
+			slog.Warn("could not poll the database, trying again later", "error", err)
+			continue
+		}
+		if current == summary {
+			continue
+		}
+		currentNovels, err := tracker.Novels()
+		if err != nil {
+			slog.Warn("could not poll the database, trying again later", "error", err)
+			continue
+		}
+		changed := changedNovels(novelSummaries, currentNovels)
+		ids := sortedIDs(changed)
+		slog.Info("database changed, rebuilding", "novel_ids", ids)
+
+		fresh, err := database.FetchNovelChapters(db, variants.Columns(chapterVariants), ids)
+		if err != nil {
+			slog.Warn("could not fetch the changed chapters, trying again later", "error", err)
+			continue
+		}
+		next := replaceChapters(chapters, changed, fresh)
+		if err := watchBuild(cfg, db, chapterVariants, next, changed); err != nil {
+			slog.Error("rebuild failed, trying again later", "error", err)
+			continue
+		}
+		// Only now, so that a failed rebuild is attempted again
+		summary, novelSummaries, chapters = current, currentNovels, next
+		for id := range changed {
+			if _, ok := currentNovels[id]; !ok {
+				slog.Warn("a novel was deleted; its pages stay in the output directory until it is cleaned", "novel_id", id)
+			}
+		}
+	}
+}
+
+// watchBuild generates the site from chapters and the current novels,
+// characters and content pages, writing the novel, volume and chapter pages of
+// the novels in only (or of all novels when only is nil).
+func watchBuild(cfg *config.Config, db *sql.DB, chapterVariants []*models.Variant, chapters []*models.Chapter, only map[int]bool) error {
+	novels, err := database.FetchAllNovels(db)
+	if err != nil {
+		return err
+	}
+	characters, err := database.FetchAllCharacters(db)
+	if err != nil {
+		return err
+	}
+	gen, err := newGenerator(cfg, novels, chapters)
+	if err != nil {
+		return err
+	}
+	gen.Variants = chapterVariants
+	gen.Characters = characters
+	gen.OnlyNovels = only
+	if gen.Pages, err = content.LoadPages(cfg.ContentDir); err != nil {
+		return fmt.Errorf("could not load content pages: %w", err)
+	}
+	return gen.GenerateSite()
+}
+
+// changedNovels returns the IDs of the novels added, removed or changed
+// between two polls.
+func changedNovels(before, after map[int]database.NovelSummary) map[int]bool {
+	changed := make(map[int]bool)
+	for id, summary := range after {
+		if prev, ok := before[id]; !ok || prev != summary {
+			changed[id] = true
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			changed[id] = true
+		}
+	}
+	return changed
+}
+
+// replaceChapters returns chapters with those of the novels in ids replaced by
+// fresh. The order of each novel's chapters is kept, which is all the
+// generator relies on.
+func replaceChapters(chapters []*models.Chapter, ids map[int]bool, fresh []*models.Chapter) []*models.Chapter {
+	kept := make([]*models.Chapter, 0, len(chapters)+len(fresh))
+	for _, chapter := range chapters {
+		if !ids[chapter.NovelID] {
+			kept = append(kept, chapter)
+		}
+	}
+	return append(kept, fresh...)
+}
+
+// sortedIDs returns the keys of ids in order.
+func sortedIDs(ids map[int]bool) []int {
+	sorted := make([]int, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+	return sorted
+}